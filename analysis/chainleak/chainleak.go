@@ -0,0 +1,344 @@
+// Package chainleak defines an analyzer that detects misuse of vmchain metric chains.
+//
+// Metric chain (CounterChain, GaugeChain, ...) is taken from the pool and returns back there by terminal method
+// (Inc, Add, Update, Unregister, Bind, Start, ...). The analyzer reports:
+//   - chains that are built but never terminated, so they never return to the pool;
+//   - chains that are used after terminal method, since the chain is already reset and may be reused by other
+//     goroutine.
+package chainleak
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/cfg"
+)
+
+const doc = `check for unterminated vmchain metric chains and their reuse after terminal call
+
+Metric chains like vmchain.Chain.Counter(name).WithLabel(...) must be completed by a terminal method (Inc, Add,
+Update, ...), which returns the chain to the pool. The chain must not be used after the terminal method.`
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "chainleak",
+	Doc:      doc,
+	URL:      "https://pkg.go.dev/github.com/koykov/vmchain/analysis/chainleak",
+	Requires: []*analysis.Analyzer{inspect.Analyzer, ctrlflow.Analyzer},
+	Run:      run,
+}
+
+const pkgPath = "github.com/koykov/vmchain"
+
+// Pooled chain types.
+var chainTypes = map[string]bool{
+	"CounterChain":             true,
+	"FloatCounterChain":        true,
+	"GaugeChain":               true,
+	"HistogramChain":           true,
+	"SummaryChain":             true,
+	"PrometheusHistogramChain": true,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	insp := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	cfgs := pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs)
+
+	filter := []ast.Node{(*ast.FuncDecl)(nil), (*ast.FuncLit)(nil)}
+	insp.Preorder(filter, func(n ast.Node) {
+		var (
+			g    *cfg.CFG
+			body *ast.BlockStmt
+		)
+		switch fn := n.(type) {
+		case *ast.FuncDecl:
+			g, body = cfgs.FuncDecl(fn), fn.Body
+		case *ast.FuncLit:
+			g, body = cfgs.FuncLit(fn), fn.Body
+		}
+		if g == nil || body == nil {
+			return
+		}
+		c := checker{pass: pass}
+		c.checkLeaks(body)
+		c.checkReuse(g)
+	})
+	return nil, nil
+}
+
+type checker struct {
+	pass *analysis.Pass
+}
+
+// isChain checks if t is a pooled chain type.
+func isChain(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == pkgPath && chainTypes[obj.Name()]
+}
+
+// method returns chain method called by call and whether it's a terminal method. Returns nil if call isn't a call of
+// chain method.
+func (c *checker) method(call *ast.CallExpr) (sel *ast.SelectorExpr, terminal bool) {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || !isChain(c.pass.TypesInfo.TypeOf(sel.X)) {
+		return nil, false
+	}
+	// Builder methods return the same chain.
+	return sel, !types.Identical(c.pass.TypesInfo.TypeOf(call), c.pass.TypesInfo.TypeOf(sel.X))
+}
+
+// root returns the expression that starts the chain of builder calls ending with e.
+func (c *checker) root(e ast.Expr) ast.Expr {
+	for {
+		e = ast.Unparen(e)
+		call, ok := e.(*ast.CallExpr)
+		if !ok {
+			return e
+		}
+		sel, terminal := c.method(call)
+		if sel == nil || terminal {
+			return e
+		}
+		e = sel.X
+	}
+}
+
+// chainVar returns the variable of chain type referred by e.
+func (c *checker) chainVar(e ast.Expr) *types.Var {
+	id, ok := ast.Unparen(e).(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	v, ok := c.pass.TypesInfo.ObjectOf(id).(*types.Var)
+	if !ok || !isChain(v.Type()) {
+		return nil
+	}
+	return v
+}
+
+// produced checks if e makes new chain (not a variable or its builder call).
+func (c *checker) produced(e ast.Expr) bool {
+	if !isChain(c.pass.TypesInfo.TypeOf(e)) {
+		return false
+	}
+	r := c.root(e)
+	_, isCall := r.(*ast.CallExpr)
+	return isCall && c.chainVar(r) == nil
+}
+
+// checkLeaks reports chains that are built, but never terminated.
+func (c *checker) checkLeaks(body *ast.BlockStmt) {
+	// Variables assigned from new chains and positions of assignments.
+	assigned := make(map[*types.Var]token.Pos)
+	// Variables that are terminated or passed somewhere else.
+	handled := make(map[*types.Var]bool)
+	// Identifiers that don't handle the chain: assignment targets and roots of discarded builder calls.
+	skip := make(map[*ast.Ident]bool)
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// Closures are checked separately, but chains captured by closure are considered handled.
+			ast.Inspect(n.Body, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok {
+					if v := c.chainVar(id); v != nil {
+						handled[v] = true
+					}
+				}
+				return true
+			})
+			return false
+		case *ast.ExprStmt:
+			if !isChain(c.pass.TypesInfo.TypeOf(n.X)) {
+				break
+			}
+			if c.produced(n.X) {
+				c.pass.Reportf(n.Pos(), "metric chain is not terminated")
+			} else if id, ok := c.root(n.X).(*ast.Ident); ok {
+				skip[id] = true
+			}
+		case *ast.AssignStmt:
+			for i, lhs := range n.Lhs {
+				id, ok := lhs.(*ast.Ident)
+				if !ok {
+					continue
+				}
+				var rhs ast.Expr
+				if len(n.Rhs) == len(n.Lhs) {
+					rhs = n.Rhs[i]
+				}
+				if id.Name == "_" {
+					if rhs == nil || !isChain(c.pass.TypesInfo.TypeOf(rhs)) {
+						continue
+					}
+					if c.produced(rhs) {
+						c.pass.Reportf(rhs.Pos(), "metric chain is not terminated")
+					} else if id, ok := c.root(rhs).(*ast.Ident); ok {
+						skip[id] = true
+					}
+					continue
+				}
+				if v := c.chainVar(id); v != nil {
+					skip[id] = true
+					if rhs != nil && c.produced(rhs) {
+						if _, ok := assigned[v]; !ok {
+							assigned[v] = rhs.Pos()
+						}
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			for i, id := range n.Names {
+				if v := c.chainVar(id); v != nil && i < len(n.Values) && c.produced(n.Values[i]) {
+					skip[id] = true
+					if _, ok := assigned[v]; !ok {
+						assigned[v] = n.Values[i].Pos()
+					}
+				}
+			}
+		case *ast.Ident:
+			if v := c.chainVar(n); v != nil && !skip[n] {
+				handled[v] = true
+			}
+		}
+		return true
+	})
+	for v, pos := range assigned {
+		if !handled[v] {
+			c.pass.Reportf(pos, "metric chain assigned to %s is not terminated", v.Name())
+		}
+	}
+}
+
+// checkReuse reports uses of chain variables after terminal calls. Terminated variables propagate through control
+// flow graph, so use in a loop after terminal call in the previous iteration is reported too.
+func (c *checker) checkReuse(g *cfg.CFG) {
+	type state map[*types.Var]bool
+	out := make([]state, len(g.Blocks))
+	reported := make(map[token.Pos]bool)
+
+	// Iterate until terminated sets stabilize. Sets only grow, so it converges.
+	for changed := true; changed; {
+		changed = false
+		for _, b := range g.Blocks {
+			if !b.Live {
+				continue
+			}
+			s := make(state)
+			for _, pred := range preds(g, b) {
+				for v := range out[pred.Index] {
+					s[v] = true
+				}
+			}
+			for _, n := range b.Nodes {
+				c.transfer(n, s, reported)
+			}
+			if len(s) != len(out[b.Index]) {
+				out[b.Index] = s
+				changed = true
+			}
+		}
+	}
+}
+
+// transfer applies events of node n to the set of terminated variables and reports uses of terminated ones.
+func (c *checker) transfer(n ast.Node, terminated map[*types.Var]bool, reported map[token.Pos]bool) {
+	report := func(pos token.Pos, v *types.Var) {
+		if !reported[pos] {
+			reported[pos] = true
+			c.pass.Reportf(pos, "metric chain %s is used after terminal call", v.Name())
+		}
+	}
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			// Closures are checked separately.
+			return false
+		case *ast.DeferStmt, *ast.GoStmt:
+			// Deferred call executes at return, so it doesn't terminate the chain here. Uses are still checked.
+			var call *ast.CallExpr
+			if d, ok := n.(*ast.DeferStmt); ok {
+				call = d.Call
+			} else {
+				call = n.(*ast.GoStmt).Call
+			}
+			for _, arg := range call.Args {
+				ast.Inspect(arg, visit)
+			}
+			if v := c.chainVar(c.root(call.Fun)); v != nil && terminated[v] {
+				report(call.Pos(), v)
+			}
+			return false
+		case *ast.AssignStmt:
+			for _, rhs := range n.Rhs {
+				ast.Inspect(rhs, visit)
+			}
+			for _, lhs := range n.Lhs {
+				if v := c.chainVar(lhs); v != nil {
+					delete(terminated, v)
+				} else {
+					ast.Inspect(lhs, visit)
+				}
+			}
+			return false
+		case *ast.ValueSpec:
+			for _, val := range n.Values {
+				ast.Inspect(val, visit)
+			}
+			for _, id := range n.Names {
+				if v := c.chainVar(id); v != nil {
+					delete(terminated, v)
+				}
+			}
+			return false
+		case *ast.CallExpr:
+			sel, terminal := c.method(n)
+			if sel == nil {
+				return true
+			}
+			for _, arg := range n.Args {
+				ast.Inspect(arg, visit)
+			}
+			v := c.chainVar(c.root(sel.X))
+			if v == nil {
+				ast.Inspect(sel.X, visit)
+				return false
+			}
+			if terminated[v] {
+				report(n.Pos(), v)
+				return false
+			}
+			if terminal {
+				terminated[v] = true
+			}
+			return false
+		case *ast.Ident:
+			if v := c.chainVar(n); v != nil && terminated[v] {
+				report(n.Pos(), v)
+			}
+		}
+		return true
+	}
+	ast.Inspect(n, visit)
+}
+
+func preds(g *cfg.CFG, b *cfg.Block) []*cfg.Block {
+	var res []*cfg.Block
+	for _, p := range g.Blocks {
+		for _, s := range p.Succs {
+			if s == b {
+				res = append(res, p)
+			}
+		}
+	}
+	return res
+}
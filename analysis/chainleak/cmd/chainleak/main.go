@@ -0,0 +1,17 @@
+// Command chainleak runs chainleak analyzer.
+//
+// Usage:
+//
+//	go install github.com/koykov/vmchain/analysis/chainleak/cmd/chainleak@latest
+//	go vet -vettool=$(which chainleak) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/koykov/vmchain/analysis/chainleak"
+)
+
+func main() {
+	singlechecker.Main(chainleak.Analyzer)
+}
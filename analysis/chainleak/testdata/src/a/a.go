@@ -0,0 +1,94 @@
+package a
+
+import "github.com/koykov/vmchain"
+
+func leaks(c vmchain.Chain) {
+	c.Counter("requests_total").WithLabel("method", "GET") // want `metric chain is not terminated`
+	c.FloatCounter("bytes_total")                          // want `metric chain is not terminated`
+	vmchain.Counter("requests_total").L("method", "GET")   // want `metric chain is not terminated`
+	_ = c.Gauge("sessions", nil)                           // want `metric chain is not terminated`
+
+	h := c.Histogram("latency_seconds") // want `metric chain assigned to h is not terminated`
+	h.WithLabel("route", "/")
+
+	var s = c.Summary("size_bytes").WithLabel("route", "/") // want `metric chain assigned to s is not terminated`
+	s.WithLabel("method", "GET")
+
+	p := c.PrometheusHistogram("size_kb") // want `metric chain assigned to p is not terminated`
+	_ = p.WithLabel("route", "/")
+}
+
+func terminated(c vmchain.Chain, cond bool) vmchain.CounterChain {
+	c.Counter("requests_total").WithLabel("method", "GET").Inc()
+	c.FloatCounter("bytes_total").Add(1)
+	c.Gauge("sessions", nil).WithLabel("dir", "in").Set(1)
+	c.Histogram("latency_seconds").Update(1)
+	defer c.Summary("size_bytes").Start().Stop()
+	c.PrometheusHistogram("size_kb").Start().WithLabel("status", "ok").Stop()
+	vmchain.Counter("requests_total").Bind().Inc()
+
+	cc := c.Counter("requests_total")
+	cc.WithLabel("method", "GET")
+	if cond {
+		cc.WithLabel("status", "ok").Inc()
+	} else {
+		cc.Add(2)
+	}
+
+	h := c.Histogram("latency_seconds")
+	defer h.Update(1)
+	h.WithLabel("route", "/")
+
+	g := c.Gauge("sessions", nil)
+	go func() {
+		g.Set(1)
+	}()
+
+	ret := c.Counter("requests_total")
+	return ret.L("method", "GET")
+}
+
+func reuse(c vmchain.Chain, n int) {
+	cc := c.Counter("requests_total").WithLabel("method", "GET")
+	cc.Inc()
+	cc.Inc() // want `metric chain cc is used after terminal call`
+
+	fc := c.FloatCounter("bytes_total")
+	fc.Add(1)
+	fc.WithLabel("dir", "in").Add(1) // want `metric chain fc is used after terminal call`
+
+	g := c.Gauge("sessions", nil)
+	for i := 0; i < n; i++ {
+		g.Set(float64(i)) // want `metric chain g is used after terminal call`
+	}
+
+	h := c.Histogram("latency_seconds")
+	t := h.Start()
+	h.Update(1) // want `metric chain h is used after terminal call`
+	t.Stop()
+
+	s := c.Summary("size_bytes")
+	s.Update(1)
+	use(s) // want `metric chain s is used after terminal call`
+
+	p := c.PrometheusHistogram("size_kb")
+	p.Update(1)
+	p = c.PrometheusHistogram("size_kb")
+	p.Update(2)
+}
+
+func reuseOK(c vmchain.Chain, n int, cond bool) {
+	for i := 0; i < n; i++ {
+		cc := c.Counter("requests_total")
+		cc.Inc()
+	}
+
+	cc := c.Counter("requests_total")
+	if cond {
+		cc.Inc()
+		return
+	}
+	cc.Add(2)
+}
+
+func use(vmchain.SummaryChain) {}
@@ -0,0 +1,62 @@
+// Package vmchain is a stub of github.com/koykov/vmchain for analyzer tests.
+package vmchain
+
+import "time"
+
+type Chain interface {
+	Gauge(initName string, f func() float64) GaugeChain
+	Counter(initName string) CounterChain
+	FloatCounter(initName string) FloatCounterChain
+	Histogram(initName string) HistogramChain
+	Summary(initName string) SummaryChain
+	PrometheusHistogram(initName string) PrometheusHistogramChain
+}
+
+type Timer interface {
+	WithLabel(name, value string) Timer
+	Stop() time.Duration
+}
+
+type CounterHandle struct{}
+
+func (h *CounterHandle) Inc() {}
+
+type CounterChain interface {
+	WithLabel(name, value string) CounterChain
+	L(name, value string) CounterChain
+	Inc()
+	Add(value int)
+	Get() uint64
+	Unregister() bool
+	Bind() *CounterHandle
+}
+
+type FloatCounterChain interface {
+	WithLabel(name, value string) FloatCounterChain
+	Add(value float64)
+}
+
+type GaugeChain interface {
+	WithLabel(name, value string) GaugeChain
+	Set(value float64)
+}
+
+type HistogramChain interface {
+	WithLabel(name, value string) HistogramChain
+	Update(value float64)
+	Start() Timer
+}
+
+type SummaryChain interface {
+	WithLabel(name, value string) SummaryChain
+	Update(value float64)
+	Start() Timer
+}
+
+type PrometheusHistogramChain interface {
+	WithLabel(name, value string) PrometheusHistogramChain
+	Update(value float64)
+	Start() Timer
+}
+
+func Counter(initName string) CounterChain { return nil }
@@ -0,0 +1,139 @@
+package vmchain
+
+import (
+	"io"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// Backend resolves full metric names (name with labels) to metric primitives.
+//
+// Chain builds names and keeps resolved metrics in its own index, so backend is called only once per series (and
+// once again after unregister or TTL eviction). Backend may also implement WritePrometheus(w io.Writer) to expose its
+// metrics using Chain.Handler and io.Closer to release resources on Chain.Close.
+type Backend interface {
+	// Gauge returns gauge registered with fullName or registers new one. f is an optional callback to get gauge
+	// value.
+	Gauge(fullName string, f func() float64) GaugeMetric
+	// Counter returns counter registered with fullName or registers new one.
+	Counter(fullName string) CounterMetric
+	// FloatCounter returns float counter registered with fullName or registers new one.
+	FloatCounter(fullName string) FloatCounterMetric
+	// Histogram returns histogram registered with fullName or registers new one.
+	Histogram(fullName string) HistogramMetric
+	// Summary returns summary registered with fullName or registers new one. Zero window and empty quantiles mean
+	// backend's defaults.
+	Summary(fullName string, window time.Duration, quantiles []float64) SummaryMetric
+	// PrometheusHistogram returns Prometheus-style histogram registered with fullName or registers new one. Empty
+	// upperBounds means backend's default buckets.
+	PrometheusHistogram(fullName string, upperBounds []float64) PrometheusHistogramMetric
+	// Unregister removes metric registered with fullName.
+	Unregister(fullName string) bool
+}
+
+// Metric primitives that backends resolve full names to. VM metrics implement them natively.
+
+type GaugeMetric interface {
+	Add(value float64)
+	Set(value float64)
+	Inc()
+	Dec()
+	Get() float64
+}
+
+type CounterMetric interface {
+	Add(value int)
+	AddInt64(value int64)
+	Set(value uint64)
+	Inc()
+	Dec()
+	Get() uint64
+}
+
+type FloatCounterMetric interface {
+	Add(value float64)
+	Sub(value float64)
+	Set(value float64)
+	Get() float64
+}
+
+type HistogramMetric interface {
+	Update(value float64)
+	UpdateDuration(startTime time.Time)
+	VisitNonZeroBuckets(f func(vmrange string, count uint64))
+	Reset()
+}
+
+type SummaryMetric interface {
+	Update(value float64)
+	UpdateDuration(startTime time.Time)
+}
+
+type PrometheusHistogramMetric interface {
+	Update(value float64)
+	UpdateDuration(startTime time.Time)
+	Reset()
+}
+
+// prometheusWriter is an optional interface of backend to expose metrics in Prometheus text format.
+type prometheusWriter interface {
+	WritePrometheus(w io.Writer)
+}
+
+// vmBackend keeps metrics in VM set. It's a default backend.
+type vmBackend struct {
+	set *metrics.Set
+}
+
+// NewVMBackend makes backend that keeps metrics in VM set. If set is nil, the default VM set uses.
+func NewVMBackend(set *metrics.Set) Backend {
+	if set == nil {
+		set = metrics.GetDefaultSet()
+	}
+	return &vmBackend{set: set}
+}
+
+func (b *vmBackend) Gauge(fullName string, f func() float64) GaugeMetric {
+	return b.set.GetOrCreateGauge(fullName, f)
+}
+
+func (b *vmBackend) Counter(fullName string) CounterMetric {
+	return b.set.GetOrCreateCounter(fullName)
+}
+
+func (b *vmBackend) FloatCounter(fullName string) FloatCounterMetric {
+	return b.set.GetOrCreateFloatCounter(fullName)
+}
+
+func (b *vmBackend) Histogram(fullName string) HistogramMetric {
+	return b.set.GetOrCreateHistogram(fullName)
+}
+
+func (b *vmBackend) Summary(fullName string, window time.Duration, quantiles []float64) SummaryMetric {
+	if window > 0 || len(quantiles) > 0 {
+		if window <= 0 {
+			window = defaultSummaryWindow
+		}
+		if len(quantiles) == 0 {
+			quantiles = defaultSummaryQuantiles
+		}
+		return b.set.GetOrCreateSummaryExt(fullName, window, quantiles)
+	}
+	return b.set.GetOrCreateSummary(fullName)
+}
+
+func (b *vmBackend) PrometheusHistogram(fullName string, upperBounds []float64) PrometheusHistogramMetric {
+	if len(upperBounds) > 0 {
+		return b.set.GetOrCreatePrometheusHistogramExt(fullName, upperBounds)
+	}
+	return b.set.GetOrCreatePrometheusHistogram(fullName)
+}
+
+func (b *vmBackend) Unregister(fullName string) bool {
+	return b.set.UnregisterMetric(fullName)
+}
+
+func (b *vmBackend) WritePrometheus(w io.Writer) {
+	b.set.WritePrometheus(w)
+}
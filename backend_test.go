@@ -0,0 +1,65 @@
+package vmchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// testBackend records calls of chain to backend and keeps metrics in VM set.
+type testBackend struct {
+	Backend
+	calls  []string
+	closed bool
+}
+
+func (b *testBackend) Counter(fullName string) CounterMetric {
+	b.calls = append(b.calls, "counter "+fullName)
+	return b.Backend.Counter(fullName)
+}
+
+func (b *testBackend) Summary(fullName string, window time.Duration, quantiles []float64) SummaryMetric {
+	b.calls = append(b.calls, "summary "+fullName)
+	return b.Backend.Summary(fullName, window, quantiles)
+}
+
+func (b *testBackend) Unregister(fullName string) bool {
+	b.calls = append(b.calls, "unregister "+fullName)
+	return b.Backend.Unregister(fullName)
+}
+
+func (b *testBackend) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestBackend(t *testing.T) {
+	t.Run("custom", func(t *testing.T) {
+		be := &testBackend{Backend: NewVMBackend(metrics.NewSet())}
+		c := NewChain(WithBackend(be))
+		c.Counter("requests_total").WithLabel("method", "GET").Inc()
+		c.Counter("requests_total").WithLabel("method", "GET").Inc()
+		c.Summary("latency_seconds").Update(1)
+		assert.Equal(t, uint64(2), c.Counter("requests_total").WithLabel("method", "GET").Get())
+		assert.True(t, c.Counter("requests_total").WithLabel("method", "GET").Unregister())
+		assert.NoError(t, c.Close())
+		assert.Equal(t, []string{
+			`counter requests_total{method="GET"}`,
+			"summary latency_seconds",
+			`unregister requests_total{method="GET"}`,
+		}, be.calls)
+		assert.True(t, be.closed)
+	})
+	t.Run("vm", func(t *testing.T) {
+		set := metrics.NewSet()
+		be := NewVMBackend(set)
+		be.Summary("default_seconds", 0, nil).Update(1)
+		be.Summary("ext_seconds", time.Minute, nil).Update(1)
+		be.PrometheusHistogram("size_kb", []float64{1}).Update(1)
+		assert.Equal(t, []string{"default_seconds", "ext_seconds", "size_kb"}, set.ListMetricNames())
+		assert.True(t, be.Unregister("size_kb"))
+		assert.False(t, be.Unregister("size_kb"))
+	})
+}
@@ -1,42 +1,110 @@
 package vmchain
 
 import (
+	"fmt"
+	"strconv"
+	"time"
+
 	"github.com/koykov/byteconv"
 	"github.com/koykov/x2bytes"
 )
 
 type builder struct {
-	buf []byte
-	lc  int
+	buf   []byte
+	lc    int
+	nl    int
+	cl    int
+	voff  int
+	ls    []lspan
+	esc   EscapeMode
+	val   ValidationMode
+	srt   bool
+	dup   DuplicateMode
+	onErr func(error)
+	rep   *reported
+	fail  bool
+	done  bool
+	// Builder of disabled chain writes nothing (see chain.nop).
+	raw bool
+}
+
+// lspan represents positions of label name and value in the buffer.
+type lspan struct {
+	klo, khi, vlo, vhi int
+	// Flag indicates constant label of the chain, overflow series keeps its value.
+	fixed bool
 }
 
 func (b *builder) setName(name string) {
+	b.setPrefixedName("", name)
+}
+
+func (b *builder) setPrefixedName(prefix, name string) {
 	b.reset()
+	b.buf = append(b.buf, prefix...)
 	b.buf = append(b.buf, name...)
+	b.validate(0, false)
+	b.nl = len(b.buf)
 }
 
+// Label setters are small enough to be inlined, so builder of disabled chain pays nothing for labels. Writers do the
+// actual work.
 func (b *builder) setLabel(label, value string) {
-	if b.lc == 0 {
-		b.buf = append(b.buf, '{')
-	} else {
-		b.buf = append(b.buf, ',')
+	if !b.raw {
+		b.writeLabel(label, value)
 	}
-	b.buf = append(b.buf, label...)
-	b.buf = append(b.buf, `="`...)
-	b.buf = append(b.buf, value...)
-	b.buf = append(b.buf, '"')
-	b.lc++
 }
 
 func (b *builder) setAnyLabel(label string, value any) {
-	if b.lc == 0 {
-		b.buf = append(b.buf, '{')
-	} else {
-		b.buf = append(b.buf, ',')
+	if !b.raw {
+		b.writeAnyLabel(label, value)
 	}
-	b.buf = append(b.buf, label...)
-	b.buf = append(b.buf, `="`...)
+}
+
+func (b *builder) setIntLabel(label string, value int64) {
+	if !b.raw {
+		b.writeIntLabel(label, value)
+	}
+}
+
+func (b *builder) setUintLabel(label string, value uint64) {
+	if !b.raw {
+		b.writeUintLabel(label, value)
+	}
+}
+
+func (b *builder) setFloatLabel(label string, value float64, format byte, prec int) {
+	if !b.raw {
+		b.writeFloatLabel(label, value, format, prec)
+	}
+}
+
+func (b *builder) setBoolLabel(label string, value bool) {
+	if !b.raw {
+		b.writeBoolLabel(label, value)
+	}
+}
 
+func (b *builder) setBytesLabel(label string, value []byte) {
+	if !b.raw {
+		b.writeBytesLabel(label, value)
+	}
+}
+
+func (b *builder) setDurationLabel(label string, value time.Duration) {
+	if !b.raw {
+		b.writeDurationLabel(label, value)
+	}
+}
+
+func (b *builder) writeLabel(label, value string) {
+	i := b.beginLabel(label)
+	b.buf = append(b.buf, value...)
+	b.endLabel(i)
+}
+
+func (b *builder) writeAnyLabel(label string, value any) {
+	i := b.beginLabel(label)
 	if value != nil {
 		var err error
 		if b.buf, err = x2bytes.ToBytes(b.buf, value); err != nil {
@@ -45,19 +113,256 @@ func (b *builder) setAnyLabel(label string, value any) {
 	} else {
 		b.buf = append(b.buf, "<nil>"...)
 	}
+	b.endLabel(i)
+}
+
+func (b *builder) writeIntLabel(label string, value int64) {
+	i := b.beginLabel(label)
+	b.buf = strconv.AppendInt(b.buf, value, 10)
+	b.endLabel(i)
+}
+
+func (b *builder) writeUintLabel(label string, value uint64) {
+	i := b.beginLabel(label)
+	b.buf = strconv.AppendUint(b.buf, value, 10)
+	b.endLabel(i)
+}
+
+func (b *builder) writeFloatLabel(label string, value float64, format byte, prec int) {
+	i := b.beginLabel(label)
+	b.buf = strconv.AppendFloat(b.buf, value, format, prec, 64)
+	b.endLabel(i)
+}
+
+func (b *builder) writeBoolLabel(label string, value bool) {
+	i := b.beginLabel(label)
+	b.buf = strconv.AppendBool(b.buf, value)
+	b.endLabel(i)
+}
+
+func (b *builder) writeBytesLabel(label string, value []byte) {
+	i := b.beginLabel(label)
+	b.buf = append(b.buf, value...)
+	b.endLabel(i)
+}
+
+func (b *builder) writeDurationLabel(label string, value time.Duration) {
+	i := b.beginLabel(label)
+	b.buf = appendDuration(b.buf, value)
+	b.endLabel(i)
+}
+
+// beginLabel writes label name and prepares the buffer to write the value.
+//
+// Returns the index of constant label with the same name or -1. In first case the value will be written to the end
+// of the buffer and moved to the place of constant label value by endLabel.
+func (b *builder) beginLabel(label string) int {
+	for i := 0; i < b.cl; i++ {
+		if ls := b.ls[i]; byteconv.B2S(b.buf[ls.klo:ls.khi]) == label {
+			b.voff = len(b.buf)
+			return i
+		}
+	}
 
+	if b.lc == 0 {
+		b.buf = append(b.buf, '{')
+	} else {
+		b.buf = append(b.buf, ',')
+	}
+	klo := len(b.buf)
+	b.appendLabel(label)
+	khi := len(b.buf)
+	b.buf = append(b.buf, `="`...)
+	b.voff = len(b.buf)
+	b.ls = append(b.ls, lspan{klo: klo, khi: khi, vlo: b.voff})
+	return -1
+}
+
+// endLabel finishes the value started by beginLabel.
+func (b *builder) endLabel(i int) {
+	b.escape(b.voff)
+	if i >= 0 {
+		b.replace(i, b.voff)
+		return
+	}
+	b.ls[len(b.ls)-1].vhi = len(b.buf)
 	b.buf = append(b.buf, '"')
 	b.lc++
 }
 
-func (b *builder) commit() string {
+// replace moves the value from buf[off:] to the place of i-th label value.
+func (b *builder) replace(i, off int) {
+	ls := &b.ls[i]
+	delta := len(b.buf) - off - (ls.vhi - ls.vlo)
+	// Cut old value, buffer becomes: head + tail + new value.
+	n := copy(b.buf[ls.vlo:], b.buf[ls.vhi:])
+	b.buf = b.buf[:ls.vlo+n]
+	// Rotate tail and new value.
+	tl := off - ls.vhi
+	reverse(b.buf[ls.vlo : ls.vlo+tl])
+	reverse(b.buf[ls.vlo+tl:])
+	reverse(b.buf[ls.vlo:])
+
+	ls.vhi += delta
+	// Value isn't constant anymore.
+	ls.fixed = false
+	for j := i + 1; j < len(b.ls); j++ {
+		b.ls[j].klo += delta
+		b.ls[j].khi += delta
+		b.ls[j].vlo += delta
+		b.ls[j].vhi += delta
+	}
+}
+
+// fix makes all labels overridable by labels with the same name.
+func (b *builder) fix() {
+	b.cl = b.lc
+}
+
+// seed copies labels of src (built with empty name) to the builder as constant labels.
+func (b *builder) seed(src *builder) {
+	if src.lc == 0 {
+		return
+	}
+	off := len(b.buf)
+	b.buf = append(b.buf, src.buf[src.nl:]...)
 	if b.lc > 0 {
+		// Builder already has labels, so replace leading brace with comma.
+		b.buf[off] = ','
+	}
+	delta := off - src.nl
+	for _, ls := range src.ls {
+		b.ls = append(b.ls, lspan{klo: ls.klo + delta, khi: ls.khi + delta, vlo: ls.vlo + delta, vhi: ls.vhi + delta, fixed: true})
+	}
+	b.lc += src.lc
+	b.cl = b.lc
+	b.fail = b.fail || src.fail
+}
+
+func (b *builder) appendLabel(label string) {
+	off := len(b.buf)
+	b.buf = append(b.buf, label...)
+	b.validate(off, true)
+}
+
+func (b *builder) validate(off int, label bool) {
+	if b.val == ValidationModeNone || validName(b.buf[off:], label) {
+		return
+	}
+	if b.val == ValidationModeReport && b.rep != nil && b.rep.has(b.buf[off:], label) {
+		// Name is known as invalid and already reported.
+		b.fail = true
+		return
+	}
+	var err error
+	if b.buf, err = validate(b.buf, off, label, b.val); err != nil {
+		if b.rep != nil {
+			b.rep.add(b.buf[off:], label)
+		}
+		b.report(err)
+	}
+}
+
+func (b *builder) escape(off int) {
+	var ok bool
+	if b.buf, ok = escapeTail(b.buf, off, b.esc); !ok {
+		b.report(fmt.Errorf("%w: %q", ErrInvalidLabelValue, b.buf[off:]))
+	}
+}
+
+// report marks the builder as failed and passes err to error handler.
+func (b *builder) report(err error) {
+	b.fail = true
+	if b.onErr != nil {
+		b.onErr(err)
+	}
+}
+
+// ok checks if built name is acceptable.
+func (b *builder) ok() bool {
+	return !b.fail && !b.raw
+}
+
+// family returns metric name without labels.
+func (b *builder) family() []byte {
+	return b.buf[:b.nl]
+}
+
+func (b *builder) commit() string {
+	if b.raw {
+		return ""
+	}
+	if !b.done {
+		if b.srt {
+			b.canonize()
+		} else if b.lc > 0 {
+			b.buf = append(b.buf, '}')
+		}
+		b.done = true
+	}
+	return byteconv.B2S(b.buf)
+}
+
+// overflow rebuilds committed name replacing all label values with OverflowValue, except chain's constant labels.
+func (b *builder) overflow() string {
+	// Build new name after the existing one and move it to the start.
+	off := len(b.buf)
+	b.buf = append(b.buf, b.buf[:b.nl]...)
+	for i := range b.ls {
+		ls := &b.ls[i]
+		if i == 0 {
+			b.buf = append(b.buf, '{')
+		} else {
+			b.buf = append(b.buf, ',')
+		}
+		klo := len(b.buf) - off
+		b.buf = append(b.buf, b.buf[ls.klo:ls.khi]...)
+		ls.klo, ls.khi = klo, len(b.buf)-off
+		b.buf = append(b.buf, `="`...)
+		vlo := len(b.buf) - off
+		if ls.fixed {
+			// Keep values of constant labels.
+			b.buf = append(b.buf, b.buf[ls.vlo:ls.vhi]...)
+		} else {
+			b.buf = append(b.buf, OverflowValue...)
+		}
+		ls.vlo, ls.vhi = vlo, len(b.buf)-off
+		b.buf = append(b.buf, '"')
+	}
+	if len(b.ls) > 0 {
 		b.buf = append(b.buf, '}')
 	}
+	n := copy(b.buf, b.buf[off:])
+	b.buf = b.buf[:n]
+	b.done = true
 	return byteconv.B2S(b.buf)
 }
 
+func (b *builder) self() *builder {
+	return b
+}
+
+// clone makes a deep copy of the builder to dst.
+func (b *builder) clone(dst *builder) {
+	buf, ls := dst.buf, dst.ls
+	*dst = *b
+	dst.buf = append(buf[:0], b.buf...)
+	dst.ls = append(ls[:0], b.ls...)
+}
+
 func (b *builder) reset() {
 	b.buf = b.buf[:0]
 	b.lc = 0
+	b.nl = 0
+	b.cl = 0
+	b.voff = 0
+	b.ls = b.ls[:0]
+	b.fail = false
+	b.done = false
+}
+
+func reverse(p []byte) {
+	for i, j := 0, len(p)-1; i < j; i, j = i+1, j-1 {
+		p[i], p[j] = p[j], p[i]
+	}
 }
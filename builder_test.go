@@ -3,6 +3,7 @@ package vmchain
 import (
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/koykov/x2bytes"
 	"github.com/stretchr/testify/assert"
@@ -137,6 +138,83 @@ func TestBuilder(t *testing.T) {
 			})
 		}
 	})
+	t.Run("escape", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			mode     EscapeMode
+			value    string
+			expected string
+			ok       bool
+		}{
+			{"clean", EscapeModeEscape, "Mozilla/5.0", `metric{label="Mozilla/5.0"}`, true},
+			{"quote", EscapeModeEscape, `say "hi"`, `metric{label="say \"hi\""}`, true},
+			{"backslash", EscapeModeEscape, `C:\Windows\`, `metric{label="C:\\Windows\\"}`, true},
+			{"newline", EscapeModeEscape, "line1\nline2\n", `metric{label="line1\nline2\n"}`, true},
+			{"injection", EscapeModeEscape, `x"} 1` + "\n" + `evil{a="`, `metric{label="x\"} 1\nevil{a=\""}`, true},
+			{"only specials", EscapeModeEscape, "\"\\\n", `metric{label="\"\\\n"}`, true},
+			{"replace", EscapeModeReplace, "a\"b\\c\nd", `metric{label="a_b_c_d"}`, true},
+			{"replace clean", EscapeModeReplace, "abc", `metric{label="abc"}`, true},
+			{"reject", EscapeModeReject, `bad"value`, ``, false},
+			{"reject clean", EscapeModeReject, "good", `metric{label="good"}`, true},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				var b builder
+				b.esc = tc.mode
+				b.setName("metric")
+				b.setLabel("label", tc.value)
+				assert.Equal(t, tc.ok, b.ok())
+				if tc.ok {
+					assert.Equal(t, tc.expected, b.commit())
+				}
+
+				b.setName("metric")
+				b.setAnyLabel("label", []byte(tc.value))
+				assert.Equal(t, tc.ok, b.ok())
+				if tc.ok {
+					assert.Equal(t, tc.expected, b.commit())
+				}
+			})
+		}
+	})
+	t.Run("escape chain", func(t *testing.T) {
+		c := NewChain(WithEscapeMode(EscapeModeReject))
+		c.Counter("vmchain_escape_reject_total").WithLabel("ua", `bad"ua`).Inc()
+		assert.Equal(t, uint64(0), c.Counter("vmchain_escape_reject_total").WithLabel("ua", `bad"ua`).Get())
+		assert.Equal(t, 0, c.(*chain).cst.len())
+	})
+	t.Run("typed labels", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			actions  func(*builder)
+			expected string
+		}{
+			{"int", func(b *builder) { b.setIntLabel("label", -42) }, `metric{label="-42"}`},
+			{"uint", func(b *builder) { b.setUintLabel("label", 18446744073709551615) }, `metric{label="18446744073709551615"}`},
+			{"float", func(b *builder) { b.setFloatLabel("label", 3.14159, 'f', 2) }, `metric{label="3.14"}`},
+			{"float exp", func(b *builder) { b.setFloatLabel("label", 1e21, 'g', -1) }, `metric{label="1e+21"}`},
+			{"bool", func(b *builder) { b.setBoolLabel("label", true) }, `metric{label="true"}`},
+			{"bytes", func(b *builder) { b.setBytesLabel("label", []byte(`say "hi"`)) }, `metric{label="say \"hi\""}`},
+			{"duration", func(b *builder) { b.setDurationLabel("label", 1500*time.Millisecond) }, `metric{label="1.5s"}`},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				var b builder
+				b.setName("metric")
+				tc.actions(&b)
+				assert.Equal(t, tc.expected, b.commit())
+			})
+		}
+	})
+	t.Run("duration", func(t *testing.T) {
+		for _, d := range []time.Duration{
+			0, 1, -1, 999, time.Microsecond, 1100 * time.Nanosecond, 2200 * time.Microsecond, time.Second,
+			4*time.Minute + 5*time.Second + 6*time.Millisecond, 7*time.Hour + 8*time.Minute + 9*time.Nanosecond,
+			-90 * time.Minute, 1<<63 - 1, -1 << 63,
+		} {
+			assert.Equal(t, d.String(), string(appendDuration(nil, d)))
+		}
+	})
 }
 
 func BenchmarkBuilder(b *testing.B) {
@@ -165,4 +243,29 @@ func BenchmarkBuilder(b *testing.B) {
 			_ = bb.commit()
 		}
 	})
+	b.Run("typed labels", func(b *testing.B) {
+		b.ReportAllocs()
+		var bb builder
+		for i := 0; i < b.N; i++ {
+			bb.reset()
+			bb.setName("custom_metric")
+			bb.setIntLabel("int_label", 123456789)
+			bb.setUintLabel("uint_label", 987654321)
+			bb.setFloatLabel("float_label", 3.14159, 'f', -1)
+			bb.setBoolLabel("bool_label", true)
+			bb.setBytesLabel("bytes_label", []byte("value"))
+			bb.setDurationLabel("duration_label", 1500*time.Millisecond)
+			_ = bb.commit()
+		}
+	})
+	b.Run("escape", func(b *testing.B) {
+		b.ReportAllocs()
+		var bb builder
+		for i := 0; i < b.N; i++ {
+			bb.reset()
+			bb.setName("http_errors_total")
+			bb.setLabel("error", `open "C:\\tmp": access denied`+"\n")
+			_ = bb.commit()
+		}
+	})
 }
@@ -0,0 +1,71 @@
+package vmchain
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/koykov/byteconv"
+)
+
+// DuplicateMode defines how to handle duplicate label names in canonical mode (see WithCanonicalLabels).
+type DuplicateMode uint8
+
+const (
+	// DuplicateModeLastWins keeps the last added label of duplicates (default).
+	DuplicateModeLastWins DuplicateMode = iota
+	// DuplicateModeError passes ErrDuplicateLabel to error handler (see WithErrorHandler) and drops the metric.
+	DuplicateModeError
+)
+
+var ErrDuplicateLabel = errors.New("duplicate label name")
+
+// canonize sorts labels by name, removes duplicates and closes the name.
+func (b *builder) canonize() {
+	// Stable insertion sort keeps the order of duplicates, so the last added label goes last.
+	for i := 1; i < len(b.ls); i++ {
+		for j := i; j > 0 && b.key(j) < b.key(j-1); j-- {
+			b.ls[j], b.ls[j-1] = b.ls[j-1], b.ls[j]
+		}
+	}
+
+	// Build new name after the existing one and move it to the start.
+	off := len(b.buf)
+	b.buf = append(b.buf, b.buf[:b.nl]...)
+	var n int
+	for i := 0; i < len(b.ls); i++ {
+		if i+1 < len(b.ls) && b.key(i) == b.key(i+1) {
+			if b.dup == DuplicateModeError {
+				b.report(fmt.Errorf("%w: %s", ErrDuplicateLabel, b.key(i)))
+			}
+			continue
+		}
+		ls := b.ls[i]
+		if n == 0 {
+			b.buf = append(b.buf, '{')
+		} else {
+			b.buf = append(b.buf, ',')
+		}
+		nls := lspan{klo: len(b.buf) - off, fixed: ls.fixed}
+		b.buf = append(b.buf, b.buf[ls.klo:ls.khi]...)
+		nls.khi = len(b.buf) - off
+		b.buf = append(b.buf, `="`...)
+		nls.vlo = len(b.buf) - off
+		b.buf = append(b.buf, b.buf[ls.vlo:ls.vhi]...)
+		nls.vhi = len(b.buf) - off
+		b.buf = append(b.buf, '"')
+		b.ls[n] = nls
+		n++
+	}
+	if n > 0 {
+		b.buf = append(b.buf, '}')
+	}
+	m := copy(b.buf, b.buf[off:])
+	b.buf = b.buf[:m]
+	b.ls = b.ls[:n]
+	b.lc = n
+}
+
+// key returns the name of i-th label.
+func (b *builder) key(i int) string {
+	return byteconv.B2S(b.buf[b.ls[i].klo:b.ls[i].khi])
+}
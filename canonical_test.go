@@ -0,0 +1,115 @@
+package vmchain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonical(t *testing.T) {
+	t.Run("sort", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			actions  func(b *builder)
+			expected string
+		}{
+			{
+				name:     "no labels",
+				actions:  func(b *builder) {},
+				expected: `metric`,
+			},
+			{
+				name: "one label",
+				actions: func(b *builder) {
+					b.setLabel("a", "1")
+				},
+				expected: `metric{a="1"}`,
+			},
+			{
+				name: "reverse",
+				actions: func(b *builder) {
+					b.setLabel("c", "3")
+					b.setLabel("b", "2")
+					b.setLabel("a", "1")
+				},
+				expected: `metric{a="1",b="2",c="3"}`,
+			},
+			{
+				name: "mixed",
+				actions: func(b *builder) {
+					b.setLabel("status", "200")
+					b.setAnyLabel("code", 15)
+					b.setLabel("method", `"GET"`)
+				},
+				expected: `metric{code="15",method="\"GET\"",status="200"}`,
+			},
+			{
+				name: "duplicate",
+				actions: func(b *builder) {
+					b.setLabel("b", "first")
+					b.setLabel("a", "1")
+					b.setLabel("b", "second")
+					b.setLabel("b", "last")
+				},
+				expected: `metric{a="1",b="last"}`,
+			},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				var b builder
+				b.srt = true
+				b.setName("metric")
+				tc.actions(&b)
+				assert.Equal(t, tc.expected, b.commit())
+				assert.Equal(t, tc.expected, b.commit())
+			})
+		}
+	})
+	t.Run("same series", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithCanonicalLabels(DuplicateModeLastWins))
+		c.Counter("requests_total").L("a", "1").L("b", "2").Inc()
+		c.Counter("requests_total").L("b", "2").L("a", "1").Inc()
+		assert.Equal(t, []string{`requests_total{a="1",b="2"}`}, set.ListMetricNames())
+		assert.Equal(t, uint64(2), c.Counter("requests_total").L("b", "2").L("a", "1").Get())
+	})
+	t.Run("const labels", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithCanonicalLabels(DuplicateModeLastWins), WithConstLabels("service", "api"),
+			WithCardinalityLimit(1, LimitModeOverflow))
+		c.Counter("requests_total").L("method", "GET").L("zone", "a").Inc()
+		c.Counter("requests_total").L("zone", "b").L("method", "GET").Inc()
+		assert.Equal(t, []string{
+			`requests_total{method="GET",service="api",zone="a"}`,
+			`requests_total{method="__overflow__",service="api",zone="__overflow__"}`,
+		}, set.ListMetricNames())
+	})
+	t.Run("error", func(t *testing.T) {
+		set := metrics.NewSet()
+		var errs []error
+		c := NewChain(WithVMSet(set), WithCanonicalLabels(DuplicateModeError), WithErrorHandler(func(err error) {
+			errs = append(errs, err)
+		}))
+		c.Counter("requests_total").L("a", "1").L("a", "2").Inc()
+		assert.Empty(t, set.ListMetricNames())
+		assert.Len(t, errs, 1)
+		assert.True(t, errors.Is(errs[0], ErrDuplicateLabel))
+		assert.EqualError(t, errs[0], "duplicate label name: a")
+	})
+}
+
+func BenchmarkCanonical(b *testing.B) {
+	b.Run("sort", func(b *testing.B) {
+		b.ReportAllocs()
+		c := NewChain(WithVMSet(metrics.NewSet()), WithCanonicalLabels(DuplicateModeLastWins))
+		for i := 0; i < b.N; i++ {
+			c.Counter("requests_total").
+				WithLabel("status", "200").
+				WithLabel("path", "/api/v1/users").
+				WithLabel("method", "GET").
+				Inc()
+		}
+	})
+}
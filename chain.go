@@ -1,10 +1,15 @@
 package vmchain
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
 	"sync"
+	"sync/atomic"
+	"time"
 	"unsafe"
 
-	"github.com/VictoriaMetrics/metrics"
 	"github.com/koykov/byteconv"
 )
 
@@ -26,51 +31,191 @@ type Chain interface {
 	Histogram(initName string) HistogramChain
 	// H is a shorthand version of Histogram.
 	H(initName string) HistogramChain
+	// Summary initialize with initName a summary chain and return it.
+	Summary(initName string) SummaryChain
+	// S is a shorthand version of Summary.
+	S(initName string) SummaryChain
+	// SummaryExt initialize with initName a summary chain with custom window and quantiles and return it.
+	SummaryExt(initName string, window time.Duration, quantiles []float64) SummaryChain
+	// SE is a shorthand version of SummaryExt.
+	SE(initName string, window time.Duration, quantiles []float64) SummaryChain
+	// PrometheusHistogram initialize with initName a Prometheus-style histogram chain with default buckets and return it.
+	PrometheusHistogram(initName string) PrometheusHistogramChain
+	// PH is a shorthand version of PrometheusHistogram.
+	PH(initName string) PrometheusHistogramChain
+	// PrometheusHistogramExt initialize with initName a Prometheus-style histogram chain with given buckets upper
+	// bounds and return it.
+	PrometheusHistogramExt(initName string, upperBounds []float64) PrometheusHistogramChain
+	// PHE is a shorthand version of PrometheusHistogramExt.
+	PHE(initName string, upperBounds []float64) PrometheusHistogramChain
+	// SetEnabled enables or disables the chain at runtime. Disabled chain and all its sub-chains, templates and handles
+	// do nothing: chains don't build names and don't touch indexes and backend.
+	//
+	// Disabled chain doesn't record labels to keep its cost near zero, so handles bound by disabled chain stay no-op
+	// even after enabling. Handles bound before disabling start to work again after enabling.
+	//
+	// Sub-chain has own flag: it's disabled if it or any of its parents is disabled.
+	SetEnabled(enabled bool)
+	// Enabled checks if the chain and all its parents are enabled.
+	Enabled() bool
+	// FamilyStats returns series statistics of metric family initName.
+	FamilyStats(initName string) FamilyStats
+	// Template makes a prototype of metric chains with initName and fixed labels. labels is a list of label names
+	// and values: name0, value0, name1, value1, ...
+	Template(initName string, labels ...string) *Template
+	// Sub returns sub-chain that adds subsystem segment to the namespace of metrics names. Sub-chain shares storage
+	// and backend with the parent chain.
+	Sub(subsystem string) Chain
+	// UnregisterFamily unregisters all series of metric family initName and returns their number.
+	UnregisterFamily(initName string) int
+	// WritePrometheus writes series of the chain in Prometheus text format to w.
+	WritePrometheus(w io.Writer)
+	// Handler returns HTTP handler that exposes series of the chain.
+	//
+	// Handler supports Prometheus text and OpenMetrics formats (depending on Accept header) and gzip compression.
+	// OpenMetrics output has no metadata, so all metrics are exposed with unknown type. Query parameter "family"
+	// filters exposed metric families and may be repeated. Handler of sub-chain exposes only series of the sub-chain
+	// and its own sub-chains.
+	Handler() http.Handler
+	// Sweep unregisters series that weren't used longer than TTL (see WithTTL) and returns their number.
+	Sweep() int
+	// Close stops background jobs of the chain and flushes metrics to StatsD agent (see WithStatsD).
+	Close() error
 }
 
 type chain struct {
-	gpool, cpool, fpool, hpool sync.Pool
-	gmux, cmux, fmux, hmux     sync.RWMutex
-	gmap, cmap, fmap, hmap     map[string]any
+	*registry
+	gpool, cpool, fpool, hpool, spool, ppool sync.Pool
+
+	pfx   string
+	cls   []string
+	cb    builder
+	esc   EscapeMode
+	val   ValidationMode
+	srt   bool
+	dup   DuplicateMode
+	onErr func(error)
+
+	smux sync.Mutex
+	subs map[string]*chain
+
+	parent *chain
+	off    atomic.Bool
+	// Shared metric chains of disabled chain, see nops.
+	nop nops
+}
+
+// nops is a set of metric chains returned by disabled chain instead of pooled ones. They are never modified after
+// init, so may be used concurrently, and do nothing.
+type nops struct {
+	g gauge
+	c counter
+	f fcounter
+	h histogram
+	s summary
+	p phistogram
+}
 
-	vmset *metrics.Set
-	gnew  func(string, func() float64) *metrics.Gauge
-	cnew  func(string) *metrics.Counter
-	fnew  func(string) *metrics.FloatCounter
-	hnew  func(string) *metrics.Histogram
+// registry is a storage of metrics shared between chain and its sub-chains.
+type registry struct {
+	gst, cst, fst, hst, sst, pst storage
+	lim                          limiter
+	rep                          reported
+
+	bnew   func() Backend
+	be     Backend
+	shards uint
+	ttl    time.Duration
+	swi    time.Duration
+	done   chan struct{}
+	once   sync.Once
 }
 
 // NewChain makes a new chain set.
 func NewChain(options ...Option) Chain {
-	c := &chain{
-		gmap: make(map[string]any),
-		cmap: make(map[string]any),
-		fmap: make(map[string]any),
-		hmap: make(map[string]any),
-
-		gnew: metrics.GetOrCreateGauge,
-		cnew: metrics.GetOrCreateCounter,
-		fnew: metrics.GetOrCreateFloatCounter,
-		hnew: metrics.GetOrCreateHistogram,
+	c := &chain{registry: &registry{}}
+	for _, fn := range options {
+		fn(c)
+	}
+	if c.bnew != nil {
+		c.be = c.bnew()
+	}
+	if c.be == nil {
+		c.be = NewVMBackend(nil)
+	}
+	if len(c.pfx) > 0 && len(c.lim.flim) > 0 {
+		// Family limits are relative to the namespace.
+		flim := make(map[string]uint64, len(c.lim.flim))
+		for initName, limit := range c.lim.flim {
+			flim[c.pfx+initName] = limit
+		}
+		c.lim.flim = flim
+	}
+	c.init()
+	if c.shards == 0 {
+		c.shards = uint(runtime.GOMAXPROCS(0))
 	}
+	for _, st := range c.storages() {
+		st.init(c.shards)
+	}
+	if c.ttl > 0 && c.swi > 0 {
+		c.done = make(chan struct{})
+		go c.sweeper()
+	}
+	return c
+}
+
+// NewNopChain makes a disabled chain, that may be enabled later using Chain.SetEnabled.
+func NewNopChain(options ...Option) Chain {
+	return NewChain(append(options, WithDisabled())...)
+}
+
+func (c *chain) init() {
 	c.gpool = sync.Pool{New: func() any { return &gauge{} }}
 	c.cpool = sync.Pool{New: func() any { return &counter{} }}
 	c.fpool = sync.Pool{New: func() any { return &fcounter{} }}
 	c.hpool = sync.Pool{New: func() any { return &histogram{} }}
-	for _, fn := range options {
-		fn(c)
+	c.spool = sync.Pool{New: func() any { return &summary{} }}
+	c.ppool = sync.Pool{New: func() any { return &phistogram{} }}
+	if len(c.cls) > 0 {
+		c.cb.esc, c.cb.val, c.cb.onErr, c.cb.rep = c.esc, c.val, c.onErr, &c.rep
+		for i := 0; i < len(c.cls); i += 2 {
+			c.cb.setLabel(c.cls[i], c.cls[i+1])
+		}
 	}
-	if c.vmset != nil {
-		c.gnew = c.vmset.GetOrCreateGauge
-		c.cnew = c.vmset.GetOrCreateCounter
-		c.fnew = c.vmset.GetOrCreateFloatCounter
-		c.hnew = c.vmset.GetOrCreateHistogram
+	ptr := c.ptr()
+	c.nop.g.sptr, c.nop.c.sptr, c.nop.f.sptr, c.nop.h.sptr, c.nop.s.sptr, c.nop.p.sptr = ptr, ptr, ptr, ptr, ptr, ptr
+	c.nop.g.raw, c.nop.c.raw, c.nop.f.raw, c.nop.h.raw, c.nop.s.raw, c.nop.p.raw = true, true, true, true, true, true
+}
+
+func (c *chain) Sub(subsystem string) Chain {
+	// Sub-chains are cached, since metric chains refer the chain by pointer and don't keep it alive.
+	c.smux.Lock()
+	defer c.smux.Unlock()
+	if sub, ok := c.subs[subsystem]; ok {
+		return sub
 	}
-	return c
+	sub := &chain{
+		registry: c.registry,
+		pfx:      c.pfx + subsystem + "_",
+		cls:      c.cls,
+		esc:      c.esc,
+		val:      c.val,
+		srt:      c.srt,
+		dup:      c.dup,
+		onErr:    c.onErr,
+		parent:   c,
+	}
+	sub.init()
+	if c.subs == nil {
+		c.subs = make(map[string]*chain)
+	}
+	c.subs[subsystem] = sub
+	return sub
 }
 
 func (c *chain) Gauge(initName string, f func() float64) GaugeChain {
-	return c.acquireGauge(initName, f)
+	return c.acquireGauge(nil, initName, f)
 }
 
 func (c *chain) G(initName string, f func() float64) GaugeChain {
@@ -78,7 +223,7 @@ func (c *chain) G(initName string, f func() float64) GaugeChain {
 }
 
 func (c *chain) Counter(initName string) CounterChain {
-	return c.acquireCounter(initName)
+	return c.acquireCounter(nil, initName)
 }
 
 func (c *chain) C(initName string) CounterChain {
@@ -86,7 +231,7 @@ func (c *chain) C(initName string) CounterChain {
 }
 
 func (c *chain) FloatCounter(initName string) FloatCounterChain {
-	return c.acquireFCounter(initName)
+	return c.acquireFCounter(nil, initName)
 }
 
 func (c *chain) FC(initName string) FloatCounterChain {
@@ -94,168 +239,438 @@ func (c *chain) FC(initName string) FloatCounterChain {
 }
 
 func (c *chain) Histogram(initName string) HistogramChain {
-	return c.acquireHistogram(initName)
+	return c.acquireHistogram(nil, initName)
 }
 
 func (c *chain) H(initName string) HistogramChain {
 	return c.Histogram(initName)
 }
 
-func (c *chain) acquireGauge(initName string, f func() float64) *gauge {
+func (c *chain) Summary(initName string) SummaryChain {
+	return c.acquireSummary(nil, initName, 0, nil)
+}
+
+func (c *chain) S(initName string) SummaryChain {
+	return c.Summary(initName)
+}
+
+func (c *chain) SummaryExt(initName string, window time.Duration, quantiles []float64) SummaryChain {
+	return c.acquireSummary(nil, initName, window, quantiles)
+}
+
+func (c *chain) SE(initName string, window time.Duration, quantiles []float64) SummaryChain {
+	return c.SummaryExt(initName, window, quantiles)
+}
+
+func (c *chain) PrometheusHistogram(initName string) PrometheusHistogramChain {
+	return c.acquirePHistogram(nil, initName, nil)
+}
+
+func (c *chain) PH(initName string) PrometheusHistogramChain {
+	return c.PrometheusHistogram(initName)
+}
+
+func (c *chain) PrometheusHistogramExt(initName string, upperBounds []float64) PrometheusHistogramChain {
+	return c.acquirePHistogram(nil, initName, upperBounds)
+}
+
+func (c *chain) PHE(initName string, upperBounds []float64) PrometheusHistogramChain {
+	return c.PrometheusHistogramExt(initName, upperBounds)
+}
+
+func (c *chain) SetEnabled(enabled bool) {
+	c.off.Store(!enabled)
+}
+
+func (c *chain) Enabled() bool {
+	return !c.disabled()
+}
+
+func (c *chain) disabled() bool {
+	for ; c != nil; c = c.parent {
+		if c.off.Load() {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *chain) FamilyStats(initName string) FamilyStats {
+	return c.lim.stats(c.pfx + initName)
+}
+
+func (c *chain) UnregisterFamily(initName string) (n int) {
+	initName = c.pfx + initName
+	for _, st := range c.storages() {
+		for i := range st.shards {
+			sh := &st.shards[i]
+			sh.mux.Lock()
+			for fullName, e := range sh.idx {
+				if fullName == initName || (len(fullName) > len(initName) && fullName[len(initName)] == '{' &&
+					fullName[:len(initName)] == initName) {
+					c.evict(sh, fullName, e)
+					n++
+				}
+			}
+			sh.mux.Unlock()
+		}
+	}
+	return
+}
+
+func (c *chain) acquireGauge(proto *builder, initName string, f func() float64) *gauge {
+	if c.disabled() {
+		return &c.nop.g
+	}
 	g := c.gpool.Get().(*gauge)
 	g.sptr = c.ptr()
-	g.setName(initName)
+	c.prepareBuilder(&g.builder, proto, initName)
 	g.f = f
 	return g
 }
 
 func (c *chain) releaseGauge(g GaugeChain) {
-	if gg, ok := any(g).(*gauge); ok {
+	if gg, ok := any(g).(*gauge); ok && !gg.raw {
 		gg.reset()
 		c.gpool.Put(g)
 	}
 }
 
-func (c *chain) getGauge(fullName string, f func() float64) *metrics.Gauge {
+func (c *chain) getGauge(b *builder, f func() float64) GaugeMetric {
+	fullName := b.commit()
+	if !b.ok() {
+		return nil
+	}
+
 	// Fast check.
-	c.gmux.RLock()
-	raw, ok := c.gmap[fullName]
-	c.gmux.RUnlock()
-	if ok {
-		return raw.(*metrics.Gauge)
+	if e := c.lookup(&c.gst, fullName); e != nil {
+		return e.m.(GaugeMetric)
 	}
 
 	// Slow path.
-	c.gmux.Lock()
-	defer c.gmux.Unlock()
-
-	// Double check.
-	if raw, ok = c.gmap[fullName]; ok {
-		// Double check passed.
-		return raw.(*metrics.Gauge)
+	e := c.register(&c.gst, b, func(fullName string) any {
+		return c.be.Gauge(fullName, f)
+	})
+	if e == nil {
+		return nil
 	}
-
-	cpy := scopy(fullName)
-	g := c.gnew(cpy, f)
-	c.gmap[cpy] = g
-	return g
+	return e.m.(GaugeMetric)
 }
 
-func (c *chain) acquireCounter(initName string) *counter {
+func (c *chain) acquireCounter(proto *builder, initName string) *counter {
+	if c.disabled() {
+		return &c.nop.c
+	}
 	cc := c.cpool.Get().(*counter)
 	cc.sptr = c.ptr()
-	cc.setName(initName)
+	c.prepareBuilder(&cc.builder, proto, initName)
 	return cc
 }
 
 func (c *chain) releaseCounter(cc CounterChain) {
-	if cc_, ok := any(cc).(*counter); ok {
+	if cc_, ok := any(cc).(*counter); ok && !cc_.raw {
 		cc_.reset()
 		c.cpool.Put(cc)
 	}
 }
 
-func (c *chain) getCounter(fullName string) *metrics.Counter {
+func (c *chain) getCounter(b *builder) CounterMetric {
+	fullName := b.commit()
+	if !b.ok() {
+		return nil
+	}
+
 	// Fast check.
-	c.cmux.RLock()
-	raw, ok := c.cmap[fullName]
-	c.cmux.RUnlock()
-	if ok {
-		return raw.(*metrics.Counter)
+	if e := c.lookup(&c.cst, fullName); e != nil {
+		return e.m.(CounterMetric)
 	}
 
 	// Slow path.
-	c.cmux.Lock()
-	defer c.cmux.Unlock()
-
-	// Double check.
-	if raw, ok = c.cmap[fullName]; ok {
-		// Double check passed.
-		return raw.(*metrics.Counter)
+	e := c.register(&c.cst, b, func(fullName string) any {
+		return c.be.Counter(fullName)
+	})
+	if e == nil {
+		return nil
 	}
-
-	cpy := scopy(fullName)
-	cc := c.cnew(cpy)
-	c.cmap[cpy] = cc
-	return cc
+	return e.m.(CounterMetric)
 }
 
-func (c *chain) acquireFCounter(initName string) *fcounter {
+func (c *chain) acquireFCounter(proto *builder, initName string) *fcounter {
+	if c.disabled() {
+		return &c.nop.f
+	}
 	cc := c.fpool.Get().(*fcounter)
 	cc.sptr = c.ptr()
-	cc.setName(initName)
+	c.prepareBuilder(&cc.builder, proto, initName)
 	return cc
 }
 
 func (c *chain) releaseFCounter(cc FloatCounterChain) {
-	if cc_, ok := any(cc).(*fcounter); ok {
+	if cc_, ok := any(cc).(*fcounter); ok && !cc_.raw {
 		cc_.reset()
 		c.fpool.Put(cc)
 	}
 }
 
-func (c *chain) getFCounter(fullName string) *metrics.FloatCounter {
+func (c *chain) getFCounter(b *builder) FloatCounterMetric {
+	fullName := b.commit()
+	if !b.ok() {
+		return nil
+	}
+
 	// Fast check.
-	c.fmux.RLock()
-	raw, ok := c.fmap[fullName]
-	c.fmux.RUnlock()
-	if ok {
-		return raw.(*metrics.FloatCounter)
+	if e := c.lookup(&c.fst, fullName); e != nil {
+		return e.m.(FloatCounterMetric)
 	}
 
 	// Slow path.
-	c.fmux.Lock()
-	defer c.fmux.Unlock()
-
-	// Double check.
-	if raw, ok = c.fmap[fullName]; ok {
-		// Double check passed.
-		return raw.(*metrics.FloatCounter)
+	e := c.register(&c.fst, b, func(fullName string) any {
+		return c.be.FloatCounter(fullName)
+	})
+	if e == nil {
+		return nil
 	}
-
-	cpy := scopy(fullName)
-	cc := c.fnew(cpy)
-	c.fmap[cpy] = cc
-	return cc
+	return e.m.(FloatCounterMetric)
 }
 
-func (c *chain) acquireHistogram(initName string) *histogram {
+func (c *chain) acquireHistogram(proto *builder, initName string) *histogram {
+	if c.disabled() {
+		return &c.nop.h
+	}
 	h := c.hpool.Get().(*histogram)
 	h.sptr = c.ptr()
-	h.setName(initName)
+	c.prepareBuilder(&h.builder, proto, initName)
 	return h
 }
 
 func (c *chain) releaseHistogram(h HistogramChain) {
-	if hh, ok := any(h).(*histogram); ok {
+	if hh, ok := any(h).(*histogram); ok && !hh.raw {
 		hh.reset()
 		c.hpool.Put(h)
 	}
 }
 
-func (c *chain) getHistogram(fullName string) *metrics.Histogram {
+func (c *chain) getHistogram(b *builder) HistogramMetric {
+	fullName := b.commit()
+	if !b.ok() {
+		return nil
+	}
+
 	// Fast check.
-	c.hmux.RLock()
-	raw, ok := c.hmap[fullName]
-	c.hmux.RUnlock()
-	if ok {
-		return raw.(*metrics.Histogram)
+	if e := c.lookup(&c.hst, fullName); e != nil {
+		return e.m.(HistogramMetric)
+	}
+
+	// Slow path.
+	e := c.register(&c.hst, b, func(fullName string) any {
+		return c.be.Histogram(fullName)
+	})
+	if e == nil {
+		return nil
+	}
+	return e.m.(HistogramMetric)
+}
+
+func (c *chain) acquireSummary(proto *builder, initName string, window time.Duration, quantiles []float64) *summary {
+	if c.disabled() {
+		return &c.nop.s
+	}
+	s := c.spool.Get().(*summary)
+	s.sptr = c.ptr()
+	c.prepareBuilder(&s.builder, proto, initName)
+	s.window, s.quantiles = window, quantiles
+	return s
+}
+
+func (c *chain) releaseSummary(s SummaryChain) {
+	if ss, ok := any(s).(*summary); ok && !ss.raw {
+		ss.reset()
+		c.spool.Put(s)
+	}
+}
+
+func (c *chain) getSummary(b *builder, window time.Duration, quantiles []float64) SummaryMetric {
+	fullName := b.commit()
+	if !b.ok() {
+		return nil
+	}
+
+	// Fast check.
+	if e := c.lookup(&c.sst, fullName); e != nil {
+		return e.m.(SummaryMetric)
 	}
 
 	// Slow path.
-	c.hmux.Lock()
-	defer c.hmux.Unlock()
+	e := c.register(&c.sst, b, func(fullName string) any {
+		return c.be.Summary(fullName, window, quantiles)
+	})
+	if e == nil {
+		return nil
+	}
+	return e.m.(SummaryMetric)
+}
+
+func (c *chain) acquirePHistogram(proto *builder, initName string, upperBounds []float64) *phistogram {
+	if c.disabled() {
+		return &c.nop.p
+	}
+	h := c.ppool.Get().(*phistogram)
+	h.sptr = c.ptr()
+	c.prepareBuilder(&h.builder, proto, initName)
+	h.buckets = upperBounds
+	return h
+}
+
+func (c *chain) releasePHistogram(h PrometheusHistogramChain) {
+	if hh, ok := any(h).(*phistogram); ok && !hh.raw {
+		hh.reset()
+		c.ppool.Put(h)
+	}
+}
+
+func (c *chain) getPHistogram(b *builder, upperBounds []float64) PrometheusHistogramMetric {
+	fullName := b.commit()
+	if !b.ok() {
+		return nil
+	}
+
+	// Fast check.
+	if e := c.lookup(&c.pst, fullName); e != nil {
+		return e.m.(PrometheusHistogramMetric)
+	}
+
+	// Slow path.
+	e := c.register(&c.pst, b, func(fullName string) any {
+		return c.be.PrometheusHistogram(fullName, upperBounds)
+	})
+	if e == nil {
+		return nil
+	}
+	return e.m.(PrometheusHistogramMetric)
+}
+
+// register creates new metric using fn and stores it in st.
+//
+// Cardinality limit of metric family is checked here. Returns nil if new series was dropped.
+func (c *chain) register(st *storage, b *builder, fn func(fullName string) any) *entry {
+	fam := c.lim.family(b.family(), c)
+	fullName := b.commit()
+	// Don't take the write lock if family is already full.
+	if !fam.full() {
+		if e := c.create(st, fullName, fam, false, fn); e != nil {
+			return e
+		}
+	} else if e := c.lookup(st, fullName); e != nil {
+		// Series registered before the family became full.
+		return e
+	}
+
+	fam.rejected.Add(1)
+	switch c.lim.mode {
+	case LimitModeOverflow:
+		fullName = b.overflow()
+		if e := c.lookup(st, fullName); e != nil {
+			return e
+		}
+		// Overflow series ignores the limit of family, but has own limit.
+		return c.create(st, fullName, fam, true, fn)
+	case LimitModeReport:
+		b.report(fmt.Errorf("%w: %s", ErrCardinalityLimit, b.family()))
+	}
+	return nil
+}
+
+// create stores new metric in st if family admits it. force flag indicates overflow series.
+func (c *chain) create(st *storage, fullName string, fam *family, force bool, fn func(fullName string) any) *entry {
+	sh := st.shard(fullName)
+	sh.mux.Lock()
+	defer sh.mux.Unlock()
 
 	// Double check.
-	if raw, ok = c.hmap[fullName]; ok {
+	if e, ok := sh.idx[fullName]; ok {
 		// Double check passed.
-		return raw.(*metrics.Histogram)
+		return e
+	}
+
+	if force {
+		if !fam.admitOverflow() {
+			return nil
+		}
+	} else if !fam.admit() {
+		return nil
 	}
 
 	cpy := scopy(fullName)
-	hh := c.hnew(cpy)
-	c.hmap[cpy] = hh
-	return hh
+	e := &entry{m: fn(cpy), fam: fam, ovf: force}
+	e.touch.Store(c.now())
+	sh.set(cpy, e)
+	return e
+}
+
+// lookup returns metric registered with fullName and marks it as recently used.
+func (c *chain) lookup(st *storage, fullName string) *entry {
+	e, ok := st.get(fullName)
+	if !ok {
+		return nil
+	}
+	c.touch(e)
+	return e
+}
+
+func (c *chain) touch(e *entry) {
+	if c.ttl > 0 {
+		if now := c.now(); e.touch.Load() != now {
+			e.touch.Store(now)
+		}
+	}
+}
+
+func (c *chain) now() int64 {
+	if c.ttl == 0 {
+		return 0
+	}
+	return time.Now().Unix()
+}
+
+// unregister removes metric built by b from st and backend.
+func (c *chain) unregister(st *storage, b *builder) bool {
+	fullName := b.commit()
+	if !b.ok() {
+		return false
+	}
+	return c.unregisterName(st, fullName)
+}
+
+func (c *chain) unregisterName(st *storage, fullName string) bool {
+	sh := st.shard(fullName)
+	sh.mux.Lock()
+	defer sh.mux.Unlock()
+	e, ok := sh.idx[fullName]
+	if ok {
+		c.evict(sh, fullName, e)
+	}
+	return ok
+}
+
+// prepareBuilder prepares builder to build a name of metric. If proto isn't nil, builder starts as a copy of it.
+func (c *chain) prepareBuilder(b *builder, proto *builder, initName string) {
+	if proto != nil {
+		proto.clone(b)
+		return
+	}
+	b.esc, b.val, b.onErr, b.rep, b.srt, b.dup = c.esc, c.val, c.onErr, &c.rep, c.srt, c.dup
+	b.setPrefixedName(c.pfx, initName)
+	b.seed(&c.cb)
+}
+
+// owns checks if c is a parent of sub-chain sub or sub itself.
+func (c *chain) owns(sub *chain) bool {
+	for ; sub != nil; sub = sub.parent {
+		if sub == c {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *chain) ptr() uintptr {
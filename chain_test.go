@@ -0,0 +1,28 @@
+package vmchain
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChain(t *testing.T) {
+	t.Run("unregister family", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithCardinalityLimit(10, LimitModeDrop))
+		c.Counter("tenant_requests_total").Inc()
+		c.Counter("tenant_requests_total").WithLabel("tenant", "a").Inc()
+		c.Counter("tenant_requests_total").WithLabel("tenant", "b").Inc()
+		c.Counter("tenant_requests_total_other").WithLabel("tenant", "a").Inc()
+		c.Histogram("tenant_requests").WithLabel("tenant", "a").Update(1)
+
+		assert.Equal(t, 3, c.UnregisterFamily("tenant_requests_total"))
+		assert.Equal(t, []string{
+			`tenant_requests_total_other{tenant="a"}`,
+			`tenant_requests{tenant="a"}`,
+		}, set.ListMetricNames())
+		assert.Equal(t, FamilyStats{}, c.FamilyStats("tenant_requests_total"))
+		assert.Equal(t, 0, c.UnregisterFamily("tenant_requests_total"))
+	})
+}
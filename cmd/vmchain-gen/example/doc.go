@@ -0,0 +1,4 @@
+// Package example contains metrics generated by vmchain-gen from metrics.yaml.
+package example
+
+//go:generate go tool vmchain-gen -spec metrics.yaml -out metrics_gen.go
@@ -0,0 +1,26 @@
+module github.com/koykov/vmchain/cmd/vmchain-gen/example
+
+go 1.24
+
+require (
+	github.com/VictoriaMetrics/metrics v1.40.2
+	github.com/koykov/vmchain v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/koykov/byteconv v1.0.1 // indirect
+	github.com/koykov/indirect v1.0.1 // indirect
+	github.com/koykov/vmchain/cmd/vmchain-gen v0.0.0-00010101000000-000000000000 // indirect
+	github.com/koykov/x2bytes v1.0.4 // indirect
+	github.com/valyala/fastrand v1.1.0 // indirect
+	github.com/valyala/histogram v1.2.0 // indirect
+	golang.org/x/sys v0.26.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+tool github.com/koykov/vmchain/cmd/vmchain-gen
+
+replace (
+	github.com/koykov/vmchain => ../../..
+	github.com/koykov/vmchain/cmd/vmchain-gen => ..
+)
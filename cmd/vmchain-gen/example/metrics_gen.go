@@ -0,0 +1,67 @@
+// Code generated by vmchain-gen from metrics.yaml. DO NOT EDIT.
+
+package example
+
+import (
+	"time"
+
+	"github.com/koykov/vmchain"
+)
+
+var metricsChain = vmchain.NewChain()
+
+// SetMetricsChain sets the chain of generated metrics. Call it before the first use of metrics.
+func SetMetricsChain(c vmchain.Chain) {
+	metricsChain = c
+}
+
+// HTTPRequests returns counter chain of http_requests_total.
+//
+// Number of HTTP requests.
+func HTTPRequests(method, status string) vmchain.CounterChain {
+	return metricsChain.Counter("http_requests_total").
+		WithLabel("method", method).
+		WithLabel("status", status)
+}
+
+// HTTPResponseSizeBytes returns float_counter chain of http_response_size_bytes_total.
+//
+// Total size of HTTP responses.
+func HTTPResponseSizeBytes(route string) vmchain.FloatCounterChain {
+	return metricsChain.FloatCounter("http_response_size_bytes_total").
+		WithLabel("route", route)
+}
+
+// DBConnections returns gauge chain of db_connections.
+//
+// Number of open DB connections.
+// Includes idle connections.
+func DBConnections(pool, type_ string) vmchain.GaugeChain {
+	return metricsChain.Gauge("db_connections", nil).
+		WithLabel("pool", pool).
+		WithLabel("type", type_)
+}
+
+// RequestDuration returns histogram chain of request_duration_seconds.
+func RequestDuration(requestID string) vmchain.HistogramChain {
+	return metricsChain.Histogram("request_duration_seconds").
+		WithLabel("request_id", requestID)
+}
+
+var payloadSizeBytesQuantiles = []float64{0.5, 0.99}
+
+// PayloadSizeBytes returns summary chain of payload_size_bytes.
+//
+// Size of request payloads.
+func PayloadSizeBytes() vmchain.SummaryChain {
+	return metricsChain.SummaryExt("payload_size_bytes", time.Minute, payloadSizeBytesQuantiles)
+}
+
+var queueWaitSecondsBuckets = []float64{0.01, 0.1, 1, 10}
+
+// QueueWaitSeconds returns prometheus_histogram chain of queue_wait_seconds.
+//
+// Time spent in queue.
+func QueueWaitSeconds() vmchain.PrometheusHistogramChain {
+	return metricsChain.PrometheusHistogramExt("queue_wait_seconds", queueWaitSecondsBuckets)
+}
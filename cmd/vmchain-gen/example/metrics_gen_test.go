@@ -0,0 +1,42 @@
+// Code generated by vmchain-gen from metrics.yaml. DO NOT EDIT.
+
+package example
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/koykov/vmchain"
+)
+
+func TestMetricsChain(t *testing.T) {
+	set := metrics.NewSet()
+	prev := metricsChain
+	SetMetricsChain(vmchain.NewChain(vmchain.WithVMSet(set)))
+	defer SetMetricsChain(prev)
+
+	HTTPRequests("method", "status").Inc()
+	HTTPResponseSizeBytes("route").Add(1)
+	DBConnections("pool", "type").Set(1)
+	RequestDuration("request_id").Update(1)
+	PayloadSizeBytes().Update(1)
+	QueueWaitSeconds().Update(1)
+
+	want := []string{
+		"db_connections{pool=\"pool\",type=\"type\"}",
+		"http_requests_total{method=\"method\",status=\"status\"}",
+		"http_response_size_bytes_total{route=\"route\"}",
+		"payload_size_bytes",
+		"queue_wait_seconds",
+		"request_duration_seconds{request_id=\"request_id\"}",
+	}
+	got := set.ListMetricNames()
+	if len(got) != len(want) {
+		t.Fatalf("got metrics %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got metric %q, want %q", got[i], want[i])
+		}
+	}
+}
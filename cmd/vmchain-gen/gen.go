@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// chainTypes maps metric types to chain interfaces.
+var chainTypes = map[string]string{
+	typeCounter:             "CounterChain",
+	typeFloatCounter:        "FloatCounterChain",
+	typeGauge:               "GaugeChain",
+	typeHistogram:           "HistogramChain",
+	typeSummary:             "SummaryChain",
+	typePrometheusHistogram: "PrometheusHistogramChain",
+}
+
+var funcs = template.FuncMap{
+	"chainType": func(m Metric) string { return chainTypes[m.Type] },
+	"params": func(m Metric) string {
+		if len(m.params) == 0 {
+			return ""
+		}
+		return strings.Join(m.params, ", ") + " string"
+	},
+	"param":   func(m Metric, i int) string { return m.params[i] },
+	"ctor":    ctor,
+	"floats":  floats,
+	"comment": comment,
+	"lower":   lower,
+	"export":  export,
+	"update":  update,
+	"oneline": oneline,
+	"labels": func(m Metric) string {
+		labels := make([]string, 0, len(m.Labels))
+		for _, label := range m.Labels {
+			labels = append(labels, "`"+label+"`")
+		}
+		return strings.Join(labels, ", ")
+	},
+}
+
+var codeTpl = template.Must(template.New("code").Funcs(funcs).Parse(`// Code generated by vmchain-gen from {{ .Source }}. DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+	{{ if .Time }}"time"
+
+	{{ end }}"github.com/koykov/vmchain"
+)
+
+var {{ .Chain }} = vmchain.NewChain()
+
+// Set{{ export .Chain }} sets the chain of generated metrics. Call it before the first use of metrics.
+func Set{{ export .Chain }}(c vmchain.Chain) {
+	{{ .Chain }} = c
+}
+{{ range .Metrics }}{{ if .Buckets }}
+var {{ lower .Func }}Buckets = []float64{ {{ floats .Buckets }} }
+{{ end }}{{ if .Quantiles }}
+var {{ lower .Func }}Quantiles = []float64{ {{ floats .Quantiles }} }
+{{ end }}
+// {{ .Func }} returns {{ .Type }} chain of {{ .Name }}.{{ if .Help }}
+//
+{{ comment .Help }}{{ end }}
+func {{ .Func }}({{ params . }}) vmchain.{{ chainType . }} {
+	return {{ $.Chain }}.{{ ctor . }}{{ $m := . }}{{ range $i, $l := .Labels }}.
+		WithLabel("{{ $l }}", {{ param $m $i }}){{ end }}
+}
+{{ end }}`))
+
+var testTpl = template.Must(template.New("test").Funcs(funcs).Parse(`// Code generated by vmchain-gen from {{ .Source }}. DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/koykov/vmchain"
+)
+
+func Test{{ export .Chain }}(t *testing.T) {
+	set := metrics.NewSet()
+	prev := {{ .Chain }}
+	Set{{ export .Chain }}(vmchain.NewChain(vmchain.WithVMSet(set)))
+	defer Set{{ export .Chain }}(prev)
+{{ range .Metrics }}
+	{{ .Func }}({{ range $i, $l := .Labels }}{{ if $i }}, {{ end }}"{{ $l }}"{{ end }}).{{ update . }}{{ end }}
+
+	want := []string{ {{- range .Names }}
+		{{ printf "%q" . }},{{ end }}
+	}
+	got := set.ListMetricNames()
+	if len(got) != len(want) {
+		t.Fatalf("got metrics %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got metric %q, want %q", got[i], want[i])
+		}
+	}
+}
+`))
+
+var docTpl = template.Must(template.New("doc").Funcs(funcs).Parse(`<!-- Code generated by vmchain-gen from {{ .Source }}. DO NOT EDIT. -->
+
+# Metrics
+
+| Metric | Type | Labels | Function | Description |
+|--------|------|--------|----------|-------------|
+{{ range .Metrics }}| ` + "`{{ .Name }}`" + ` | {{ .Type }} | {{ labels . }} | ` + "`{{ .Func }}`" + ` | {{ oneline .Help }} |
+{{ end }}`))
+
+type data struct {
+	*Spec
+	Source string
+	Time   bool
+	// Names of series registered by generated test.
+	Names []string
+}
+
+// generate makes Go code, test and markdown docs of metrics.
+func generate(spec *Spec, source string) (code, test, doc []byte, err error) {
+	d := data{Spec: spec, Source: source}
+	for _, m := range spec.Metrics {
+		if m.window > 0 {
+			d.Time = true
+		}
+		name := m.Name
+		if len(m.Labels) > 0 {
+			pairs := make([]string, 0, len(m.Labels))
+			for _, label := range m.Labels {
+				pairs = append(pairs, label+"="+strconv.Quote(label))
+			}
+			name += "{" + strings.Join(pairs, ",") + "}"
+		}
+		d.Names = append(d.Names, name)
+	}
+	sort.Strings(d.Names)
+
+	if code, err = execute(codeTpl, &d, true); err != nil {
+		return
+	}
+	if test, err = execute(testTpl, &d, true); err != nil {
+		return
+	}
+	doc, err = execute(docTpl, &d, false)
+	return
+}
+
+func execute(tpl *template.Template, d *data, gofmt bool) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, d); err != nil {
+		return nil, err
+	}
+	if !gofmt {
+		return buf.Bytes(), nil
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("cannot format generated %s: %w\n%s", tpl.Name(), err, buf.Bytes())
+	}
+	return src, nil
+}
+
+// ctor returns the call of chain method that makes metric chain.
+func ctor(m Metric) string {
+	switch m.Type {
+	case typeCounter:
+		return fmt.Sprintf("Counter(%q)", m.Name)
+	case typeFloatCounter:
+		return fmt.Sprintf("FloatCounter(%q)", m.Name)
+	case typeGauge:
+		return fmt.Sprintf("Gauge(%q, nil)", m.Name)
+	case typeHistogram:
+		return fmt.Sprintf("Histogram(%q)", m.Name)
+	case typeSummary:
+		if m.window == 0 && len(m.Quantiles) == 0 {
+			return fmt.Sprintf("Summary(%q)", m.Name)
+		}
+		window, quantiles := "0", "nil"
+		if m.window > 0 {
+			window = duration(m.window)
+		}
+		if len(m.Quantiles) > 0 {
+			quantiles = lower(m.Func) + "Quantiles"
+		}
+		return fmt.Sprintf("SummaryExt(%q, %s, %s)", m.Name, window, quantiles)
+	case typePrometheusHistogram:
+		if len(m.Buckets) == 0 {
+			return fmt.Sprintf("PrometheusHistogram(%q)", m.Name)
+		}
+		return fmt.Sprintf("PrometheusHistogramExt(%q, %sBuckets)", m.Name, lower(m.Func))
+	}
+	return ""
+}
+
+// update returns terminal call of metric chain used by generated test.
+func update(m Metric) string {
+	switch m.Type {
+	case typeCounter:
+		return "Inc()"
+	case typeFloatCounter:
+		return "Add(1)"
+	case typeGauge:
+		return "Set(1)"
+	}
+	return "Update(1)"
+}
+
+// duration returns Go expression of d using the largest unit that divides it.
+func duration(d time.Duration) string {
+	units := []struct {
+		d    time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+		{time.Microsecond, "time.Microsecond"},
+	}
+	for _, u := range units {
+		if d%u.d == 0 {
+			if d == u.d {
+				return u.name
+			}
+			return fmt.Sprintf("%d * %s", d/u.d, u.name)
+		}
+	}
+	return strconv.FormatInt(int64(d), 10)
+}
+
+func floats(a []float64) string {
+	s := make([]string, 0, len(a))
+	for _, f := range a {
+		s = append(s, strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	return strings.Join(s, ", ")
+}
+
+// oneline joins lines of s to fit in the cell of markdown table.
+func oneline(s string) string {
+	return strings.ReplaceAll(strings.Join(strings.Fields(s), " "), "|", `\|`)
+}
+
+func comment(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight("// "+lines[i], " ")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	// Generated example must be up to date, run go generate ./... otherwise.
+	dir := t.TempDir()
+	out := filepath.Join(dir, "metrics_gen.go")
+	if !assert.NoError(t, run("example/metrics.yaml", out, "example", true, true)) {
+		return
+	}
+	for _, name := range []string{"metrics_gen.go", "metrics_gen_test.go", "metrics_gen.md"} {
+		want, err := os.ReadFile(filepath.Join("example", name))
+		assert.NoError(t, err)
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		assert.NoError(t, err)
+		assert.Equal(t, string(want), string(got), name)
+	}
+}
+
+func TestSpec(t *testing.T) {
+	t.Run("names", func(t *testing.T) {
+		assert.Equal(t, "HTTPRequests", funcName("http_requests_total"))
+		assert.Equal(t, "GCPauseSeconds", funcName("gc_pause_seconds"))
+		assert.Equal(t, "NodeCPUUsage", funcName("node:cpu_usage"))
+		assert.Equal(t, "requestID", paramName("request_id"))
+		assert.Equal(t, "userURL", paramName("user_url"))
+		assert.Equal(t, "id", paramName("id"))
+		assert.Equal(t, "type_", paramName("type"))
+		assert.Equal(t, "label", paramName("_"))
+		assert.Equal(t, "metricsChain", chainName("metrics.yaml"))
+		assert.Equal(t, "httpMetricsV1Chain", chainName("conf/http-metrics.v1.yaml"))
+		assert.Equal(t, "typeChain", chainName("type.yml"))
+		assert.Equal(t, `a \| b c`, oneline("a | b\n  c"))
+	})
+	t.Run("params", func(t *testing.T) {
+		spec := Spec{Chain: "metricsChain", Metrics: []Metric{{
+			Name:    "queue_wait_seconds",
+			Type:    "prometheus_histogram",
+			Labels:  []string{"queue_wait_seconds_buckets", "metrics_chain", "queue"},
+			Buckets: []float64{1},
+		}}}
+		assert.NoError(t, spec.validate())
+		assert.Equal(t, []string{"queueWaitSecondsBuckets_", "metricsChain_", "queue"}, spec.Metrics[0].params)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, tc := range []struct {
+			name string
+			m    Metric
+			err  string
+		}{
+			{"name", Metric{Name: "http-requests", Type: "counter"}, `metric #0: invalid name "http-requests"`},
+			{"type", Metric{Name: "x", Type: "meter"}, `metric x: unknown type "meter"`},
+			{"label", Metric{Name: "x", Type: "counter", Labels: []string{"a-b"}}, `metric x: invalid label name "a-b"`},
+			{"duplicate label", Metric{Name: "x", Type: "counter", Labels: []string{"a", "a"}}, "metric x: duplicate label a"},
+			{"buckets", Metric{Name: "x", Type: "histogram", Buckets: []float64{1}}, "metric x: buckets are allowed only for prometheus_histogram"},
+			{"unsorted buckets", Metric{Name: "x", Type: "prometheus_histogram", Buckets: []float64{2, 1}}, "metric x: buckets must be sorted"},
+			{"window", Metric{Name: "x", Type: "summary", Window: "5"}, `metric x: invalid window "5"`},
+			{"quantile", Metric{Name: "x", Type: "summary", Quantiles: []float64{2}}, "metric x: quantile 2 out of range [0, 1]"},
+			{"func", Metric{Name: "x", Func: "lower", Type: "counter"}, `metric x: invalid func name "lower"`},
+			{"generated func", Metric{Name: "set_chain", Type: "counter"}, "metric set_chain: func name SetChain clashes with generated code"},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				spec := Spec{Metrics: []Metric{tc.m}}
+				assert.EqualError(t, spec.validate(), tc.err)
+			})
+		}
+		spec := Spec{Metrics: []Metric{{Name: "x_total", Type: "counter"}, {Name: "x", Type: "gauge"}}}
+		assert.EqualError(t, spec.validate(), "metric x: duplicate func name X")
+		assert.EqualError(t, (&Spec{}).validate(), "no metrics defined")
+		spec = Spec{Chain: "Chain", Metrics: []Metric{{Name: "x", Type: "gauge"}}}
+		assert.EqualError(t, spec.validate(), `invalid chain name "Chain"`)
+		spec = Spec{Chain: "poolChain", Metrics: []Metric{{Name: "test_pool_chain", Type: "gauge"}}}
+		assert.EqualError(t, spec.validate(), "metric test_pool_chain: func name TestPoolChain clashes with generated code")
+	})
+}
@@ -0,0 +1,13 @@
+module github.com/koykov/vmchain/cmd/vmchain-gen
+
+go 1.22
+
+require (
+	github.com/stretchr/testify v1.11.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+)
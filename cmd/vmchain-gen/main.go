@@ -0,0 +1,86 @@
+// Command vmchain-gen generates typed functions of metrics built on vmchain.Chain.
+//
+// Metric families are defined in YAML file:
+//
+//	package: metrics
+//	metrics:
+//	  - name: http_requests_total
+//	    type: counter
+//	    help: Number of HTTP requests.
+//	    labels: [method, status]
+//
+// For each family the tool generates function like HTTPRequests(method, status string) vmchain.CounterChain. Besides
+// the code, the tool generates a test of generated functions and markdown docs of metrics.
+//
+// Generated functions use the chain kept in package variable named after the spec file: metricsChain for metrics.yaml,
+// replace it by SetMetricsChain. Spec field chain sets another name, so several spec files may generate code into one
+// package.
+//
+// The tool is a separate module, so its dependencies don't get into modules of vmchain users. Add it to tools of your
+// module:
+//
+//	go get -tool github.com/koykov/vmchain/cmd/vmchain-gen
+//
+// and run with go generate:
+//
+//	//go:generate go tool vmchain-gen -spec metrics.yaml -out metrics_gen.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		specPath = flag.String("spec", "metrics.yaml", "path to YAML file with metrics definitions")
+		out      = flag.String("out", "metrics_gen.go", "output Go file; test and docs are written next to it")
+		pkg      = flag.String("pkg", "", "package name, overrides package in spec")
+		noTest   = flag.Bool("notest", false, "don't generate test")
+		noDoc    = flag.Bool("nodoc", false, "don't generate markdown docs")
+	)
+	flag.Parse()
+	if err := run(*specPath, *out, *pkg, !*noTest, !*noDoc); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "vmchain-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(specPath, out, pkg string, withTest, withDoc bool) error {
+	spec, err := loadSpec(specPath)
+	if err != nil {
+		return err
+	}
+	if len(pkg) > 0 {
+		spec.Package = pkg
+	}
+	if len(spec.Package) == 0 {
+		abs, err := filepath.Abs(filepath.Dir(out))
+		if err != nil {
+			return err
+		}
+		spec.Package = strings.ReplaceAll(filepath.Base(abs), "-", "_")
+	}
+	code, test, doc, err := generate(spec, filepath.Base(specPath))
+	if err != nil {
+		return err
+	}
+	base := strings.TrimSuffix(out, ".go")
+	if err = os.WriteFile(out, code, 0644); err != nil {
+		return err
+	}
+	if withTest {
+		if err = os.WriteFile(base+"_test.go", test, 0644); err != nil {
+			return err
+		}
+	}
+	if withDoc {
+		if err = os.WriteFile(base+".md", doc, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
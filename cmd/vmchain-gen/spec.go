@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is a definition of metric families to generate.
+type Spec struct {
+	// Package is a name of generated package.
+	Package string `yaml:"package"`
+	// Chain is a name of generated variable keeping the chain of metrics. Names of generated setter Set<Chain> and
+	// test Test<Chain> make from it. By default, it makes from spec file name: metrics.yaml -> metricsChain.
+	Chain string `yaml:"chain"`
+	// Metrics is a list of metric families.
+	Metrics []Metric `yaml:"metrics"`
+}
+
+// Metric is a definition of metric family.
+type Metric struct {
+	// Name is a metric name (initName of the chain).
+	Name string `yaml:"name"`
+	// Func is a name of generated function. By default, it makes from the name in CamelCase without _total suffix.
+	Func string `yaml:"func"`
+	// Type is a metric type: counter, float_counter, gauge, histogram, summary or prometheus_histogram.
+	Type string `yaml:"type"`
+	// Help is a description of the metric.
+	Help string `yaml:"help"`
+	// Labels is a list of label names.
+	Labels []string `yaml:"labels"`
+	// Buckets is a list of buckets upper bounds of prometheus_histogram.
+	Buckets []float64 `yaml:"buckets"`
+	// Window is a summary window, e.g. 5m.
+	Window string `yaml:"window"`
+	// Quantiles is a list of summary quantiles.
+	Quantiles []float64 `yaml:"quantiles"`
+
+	window time.Duration
+	// Go parameter names of labels.
+	params []string
+}
+
+// Metric types.
+const (
+	typeCounter             = "counter"
+	typeFloatCounter        = "float_counter"
+	typeGauge               = "gauge"
+	typeHistogram           = "histogram"
+	typeSummary             = "summary"
+	typePrometheusHistogram = "prometheus_histogram"
+)
+
+var (
+	reName  = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	reLabel = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	reFunc  = regexp.MustCompile(`^[A-Z][a-zA-Z0-9_]*$`)
+	reChain = regexp.MustCompile(`^[a-z][a-zA-Z0-9_]*$`)
+
+	// Common initialisms, see https://go.dev/wiki/CodeReviewComments#initialisms.
+	initialisms = map[string]string{
+		"api": "API", "cpu": "CPU", "db": "DB", "dns": "DNS", "gc": "GC", "grpc": "GRPC", "http": "HTTP",
+		"https": "HTTPS", "id": "ID", "io": "IO", "ip": "IP", "json": "JSON", "rpc": "RPC", "sql": "SQL",
+		"tcp": "TCP", "tls": "TLS", "ttl": "TTL", "udp": "UDP", "uri": "URI", "url": "URL",
+	}
+)
+
+func loadSpec(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec Spec
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err = dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	if len(spec.Chain) == 0 {
+		spec.Chain = chainName(path)
+	}
+	if err = spec.validate(); err != nil {
+		return nil, fmt.Errorf("invalid spec %s: %w", path, err)
+	}
+	return &spec, nil
+}
+
+func (s *Spec) validate() error {
+	if len(s.Metrics) == 0 {
+		return fmt.Errorf("no metrics defined")
+	}
+	if len(s.Chain) == 0 {
+		s.Chain = "chain"
+	}
+	if !reChain.MatchString(s.Chain) || reserved(s.Chain) {
+		return fmt.Errorf("invalid chain name %q", s.Chain)
+	}
+	names, funcs := make(map[string]struct{}), make(map[string]struct{})
+	for i := range s.Metrics {
+		m := &s.Metrics[i]
+		if !reName.MatchString(m.Name) {
+			return fmt.Errorf("metric #%d: invalid name %q", i, m.Name)
+		}
+		if _, ok := names[m.Name]; ok {
+			return fmt.Errorf("metric %s: duplicate name", m.Name)
+		}
+		names[m.Name] = struct{}{}
+
+		if len(m.Func) == 0 {
+			m.Func = funcName(m.Name)
+		}
+		if !reFunc.MatchString(m.Func) {
+			return fmt.Errorf("metric %s: invalid func name %q", m.Name, m.Func)
+		}
+		if _, ok := funcs[m.Func]; ok {
+			return fmt.Errorf("metric %s: duplicate func name %s", m.Name, m.Func)
+		}
+		if m.Func == "Set"+export(s.Chain) || m.Func == "Test"+export(s.Chain) {
+			return fmt.Errorf("metric %s: func name %s clashes with generated code", m.Name, m.Func)
+		}
+		funcs[m.Func] = struct{}{}
+
+		switch m.Type {
+		case typeCounter, typeFloatCounter, typeGauge, typeHistogram, typeSummary, typePrometheusHistogram:
+		default:
+			return fmt.Errorf("metric %s: unknown type %q", m.Name, m.Type)
+		}
+
+		// Parameters must not shadow variables used by generated function.
+		vars := map[string]struct{}{s.Chain: {}}
+		if len(m.Buckets) > 0 {
+			vars[lower(m.Func)+"Buckets"] = struct{}{}
+		}
+		if len(m.Quantiles) > 0 {
+			vars[lower(m.Func)+"Quantiles"] = struct{}{}
+		}
+		params := make(map[string]struct{}, len(m.Labels))
+		m.params = m.params[:0]
+		for _, label := range m.Labels {
+			if !reLabel.MatchString(label) {
+				return fmt.Errorf("metric %s: invalid label name %q", m.Name, label)
+			}
+			p := paramName(label)
+			if _, ok := vars[p]; ok {
+				p += "_"
+			}
+			if _, ok := params[p]; ok {
+				return fmt.Errorf("metric %s: duplicate label %s", m.Name, label)
+			}
+			params[p] = struct{}{}
+			m.params = append(m.params, p)
+		}
+
+		if len(m.Buckets) > 0 {
+			if m.Type != typePrometheusHistogram {
+				return fmt.Errorf("metric %s: buckets are allowed only for %s", m.Name, typePrometheusHistogram)
+			}
+			if !sort.Float64sAreSorted(m.Buckets) {
+				return fmt.Errorf("metric %s: buckets must be sorted", m.Name)
+			}
+		}
+		if len(m.Window) > 0 || len(m.Quantiles) > 0 {
+			if m.Type != typeSummary {
+				return fmt.Errorf("metric %s: window and quantiles are allowed only for %s", m.Name, typeSummary)
+			}
+		}
+		if len(m.Window) > 0 {
+			var err error
+			if m.window, err = time.ParseDuration(m.Window); err != nil || m.window <= 0 {
+				return fmt.Errorf("metric %s: invalid window %q", m.Name, m.Window)
+			}
+		}
+		for _, q := range m.Quantiles {
+			if q < 0 || q > 1 {
+				return fmt.Errorf("metric %s: quantile %v out of range [0, 1]", m.Name, q)
+			}
+		}
+	}
+	return nil
+}
+
+// funcName makes Go function name from metric name: http_requests_total -> HTTPRequests.
+func funcName(name string) string {
+	name = strings.TrimSuffix(name, "_total")
+	var buf strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == ':' }) {
+		if s, ok := initialisms[strings.ToLower(word)]; ok {
+			buf.WriteString(s)
+			continue
+		}
+		buf.WriteString(strings.ToUpper(word[:1]))
+		buf.WriteString(word[1:])
+	}
+	return buf.String()
+}
+
+// paramName makes Go parameter name from label name: request_id -> requestID.
+func paramName(label string) string {
+	var buf strings.Builder
+	for _, word := range strings.FieldsFunc(label, func(r rune) bool { return r == '_' }) {
+		switch s, ok := initialisms[strings.ToLower(word)]; {
+		case buf.Len() == 0:
+			buf.WriteString(strings.ToLower(word))
+		case ok:
+			buf.WriteString(s)
+		default:
+			buf.WriteString(strings.ToUpper(word[:1]))
+			buf.WriteString(word[1:])
+		}
+	}
+	s := buf.String()
+	if len(s) == 0 {
+		s = "label"
+	}
+	if reserved(s) {
+		s += "_"
+	}
+	return s
+}
+
+// reserved checks if s can't be used as parameter name of generated function.
+func reserved(s string) bool {
+	switch s {
+	case "break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough", "for", "func",
+		"go", "goto", "if", "import", "interface", "map", "package", "range", "return", "select", "struct", "switch",
+		"type", "var", "vmchain", "time":
+		return true
+	}
+	return false
+}
+
+// chainName makes the name of chain variable from spec file path: http-metrics.yaml -> httpMetricsChain.
+func chainName(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	base = strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, base)
+	return strings.TrimSuffix(paramName(base), "_") + "Chain"
+}
+
+func lower(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func export(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,107 @@
+package vmchain
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstLabels(t *testing.T) {
+	t.Run("seed", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithConstLabels("service", "api", "region", "eu"))
+		c.Counter("requests_total").Inc()
+		c.Counter("requests_total").WithLabel("method", "GET").WithAnyLabel("status", 200).Inc()
+		assert.Equal(t, []string{
+			`requests_total{service="api",region="eu",method="GET",status="200"}`,
+			`requests_total{service="api",region="eu"}`,
+		}, set.ListMetricNames())
+	})
+	t.Run("override", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			actions  func(b *builder)
+			expected string
+		}{
+			{
+				name: "first",
+				actions: func(b *builder) {
+					b.setLabel("method", "GET")
+					b.setLabel("service", "worker")
+				},
+				expected: `metric{service="worker",region="eu",method="GET"}`,
+			},
+			{
+				name: "last",
+				actions: func(b *builder) {
+					b.setAnyLabel("region", "us-east-1")
+				},
+				expected: `metric{service="api",region="us-east-1"}`,
+			},
+			{
+				name: "shorter",
+				actions: func(b *builder) {
+					b.setLabel("service", "a")
+					b.setLabel("status", "200")
+				},
+				expected: `metric{service="a",region="eu",status="200"}`,
+			},
+			{
+				name: "escaped",
+				actions: func(b *builder) {
+					b.setLabel("service", `"quoted"`)
+					b.setLabel("region", "")
+				},
+				expected: `metric{service="\"quoted\"",region=""}`,
+			},
+			{
+				name: "twice",
+				actions: func(b *builder) {
+					b.setLabel("region", "us")
+					b.setLabel("region", "asia")
+				},
+				expected: `metric{service="api",region="asia"}`,
+			},
+		}
+		c := NewChain(WithConstLabels("service", "api", "region", "eu")).(*chain)
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				var b builder
+				c.prepareBuilder(&b, nil, "metric")
+				tc.actions(&b)
+				assert.Equal(t, tc.expected, b.commit())
+			})
+		}
+	})
+	t.Run("overflow", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithConstLabels("service", "api"), WithCardinalityLimit(1, LimitModeOverflow))
+		c.Counter("requests_total").WithLabel("userID", "1").Inc()
+		c.Counter("requests_total").WithLabel("userID", "2").Inc()
+		assert.Equal(t, []string{
+			`requests_total{service="api",userID="1"}`,
+			`requests_total{service="api",userID="__overflow__"}`,
+		}, set.ListMetricNames())
+	})
+	t.Run("odd", func(t *testing.T) {
+		assert.Panics(t, func() { WithConstLabels("service") })
+	})
+}
+
+func BenchmarkConstLabels(b *testing.B) {
+	b.Run("seed", func(b *testing.B) {
+		b.ReportAllocs()
+		c := NewChain(WithVMSet(metrics.NewSet()), WithConstLabels("service", "api", "region", "eu", "instance", "host-1"))
+		for i := 0; i < b.N; i++ {
+			c.Counter("requests_total").WithLabel("method", "GET").Inc()
+		}
+	})
+	b.Run("override", func(b *testing.B) {
+		b.ReportAllocs()
+		c := NewChain(WithVMSet(metrics.NewSet()), WithConstLabels("service", "api", "region", "eu", "instance", "host-1"))
+		for i := 0; i < b.N; i++ {
+			c.Counter("requests_total").WithLabel("region", "us").Inc()
+		}
+	})
+}
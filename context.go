@@ -0,0 +1,49 @@
+package vmchain
+
+import "context"
+
+type ctxKey struct{}
+
+// ctxLabels is a list of label names and values stored in context.
+type ctxLabels struct {
+	pairs []string
+}
+
+// ContextWithLabels returns a copy of ctx with labels, that will be added to metrics by WithContext method of chains.
+// labels is a list of label names and values: name0, value0, name1, value1, ... Labels stored in the parent context
+// are kept, new values override parent values of the same labels.
+func ContextWithLabels(ctx context.Context, labels ...string) context.Context {
+	if len(labels)%2 != 0 {
+		panic("vmchain: odd number of context labels pairs")
+	}
+	var pairs []string
+	if parent, ok := ctx.Value(ctxKey{}).(*ctxLabels); ok {
+		pairs = make([]string, len(parent.pairs), len(parent.pairs)+len(labels))
+		copy(pairs, parent.pairs)
+	}
+outer:
+	for i := 0; i < len(labels); i += 2 {
+		for j := 0; j < len(pairs); j += 2 {
+			if pairs[j] == labels[i] {
+				pairs[j+1] = labels[i+1]
+				continue outer
+			}
+		}
+		pairs = append(pairs, labels[i], labels[i+1])
+	}
+	return context.WithValue(ctx, ctxKey{}, &ctxLabels{pairs: pairs})
+}
+
+// setContextLabels adds labels stored in ctx (see ContextWithLabels).
+func (b *builder) setContextLabels(ctx context.Context) {
+	if ctx == nil {
+		return
+	}
+	l, ok := ctx.Value(ctxKey{}).(*ctxLabels)
+	if !ok {
+		return
+	}
+	for i := 0; i < len(l.pairs); i += 2 {
+		b.setLabel(l.pairs[i], l.pairs[i+1])
+	}
+}
@@ -0,0 +1,59 @@
+package vmchain
+
+import (
+	"context"
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	t.Run("labels", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		ctx := ContextWithLabels(context.Background(), "tenant", "acme", "route", "/users")
+		ctx = ContextWithLabels(ctx, "route", "/orders", "bucket", "b")
+		c.Counter("requests_total").WithContext(ctx).WithLabel("method", "GET").Inc()
+		c.Gauge("sessions", nil).WithContext(ctx).Set(1)
+		c.FloatCounter("bytes_total").WithContext(ctx).Add(1)
+		c.Histogram("latency_seconds").WithContext(ctx).Update(1)
+		c.Summary("size_bytes").WithContext(ctx).Update(1)
+		c.PrometheusHistogram("size_kb").WithContext(ctx).Update(1)
+		assert.Equal(t, []string{
+			`bytes_total{tenant="acme",route="/orders",bucket="b"}`,
+			`latency_seconds{tenant="acme",route="/orders",bucket="b"}`,
+			`requests_total{tenant="acme",route="/orders",bucket="b",method="GET"}`,
+			`sessions{tenant="acme",route="/orders",bucket="b"}`,
+			`size_bytes{tenant="acme",route="/orders",bucket="b"}`,
+			`size_kb{tenant="acme",route="/orders",bucket="b"}`,
+		}, set.ListMetricNames())
+	})
+	t.Run("empty", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.Counter("requests_total").WithContext(context.Background()).Inc()
+		c.Counter("requests_total").WithContext(nil).Inc() //nolint:staticcheck
+		assert.Equal(t, []string{"requests_total"}, set.ListMetricNames())
+	})
+	t.Run("allocs", func(t *testing.T) {
+		if raceEnabled {
+			t.Skip("allocations are inaccurate under race detector")
+		}
+		c := NewChain(WithVMSet(metrics.NewSet()))
+		ctx := ContextWithLabels(context.Background(), "tenant", "acme")
+		allocs := testing.AllocsPerRun(100, func() {
+			c.Counter("requests_total").WithContext(ctx).Inc()
+		})
+		assert.Equal(t, 0.0, allocs)
+	})
+}
+
+func BenchmarkContext(b *testing.B) {
+	c := NewChain(WithVMSet(metrics.NewSet()))
+	ctx := ContextWithLabels(context.Background(), "tenant", "acme", "route", "/users")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Counter("requests_total").WithContext(ctx).WithLabel("method", "GET").Inc()
+	}
+}
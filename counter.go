@@ -1,18 +1,33 @@
 package vmchain
 
-import "github.com/koykov/indirect"
+import (
+	"context"
+	"time"
+
+	"github.com/koykov/indirect"
+)
 
 type CounterChain interface {
 	WithLabel(name, value string) CounterChain
 	L(name, value string) CounterChain
 	WithAnyLabel(name string, value any) CounterChain
 	AL(name string, value any) CounterChain
+	WithIntLabel(name string, value int64) CounterChain
+	WithUintLabel(name string, value uint64) CounterChain
+	WithFloatLabel(name string, value float64, format byte, prec int) CounterChain
+	WithBoolLabel(name string, value bool) CounterChain
+	WithBytesLabel(name string, value []byte) CounterChain
+	WithDurationLabel(name string, value time.Duration) CounterChain
+	WithContext(ctx context.Context) CounterChain
+	WithStructLabels(v any) CounterChain
 	Add(value int)
 	AddInt64(value int64)
 	Set(value uint64)
 	Inc()
 	Get() uint64
 	Dec()
+	Unregister() bool
+	Bind() *CounterHandle
 }
 
 type counter struct {
@@ -38,38 +53,88 @@ func (c *counter) AL(name string, value any) CounterChain {
 	return c.WithAnyLabel(name, value)
 }
 
+func (c *counter) WithIntLabel(name string, value int64) CounterChain {
+	c.setIntLabel(name, value)
+	return c
+}
+
+func (c *counter) WithUintLabel(name string, value uint64) CounterChain {
+	c.setUintLabel(name, value)
+	return c
+}
+
+func (c *counter) WithFloatLabel(name string, value float64, format byte, prec int) CounterChain {
+	c.setFloatLabel(name, value, format, prec)
+	return c
+}
+
+func (c *counter) WithBoolLabel(name string, value bool) CounterChain {
+	c.setBoolLabel(name, value)
+	return c
+}
+
+func (c *counter) WithBytesLabel(name string, value []byte) CounterChain {
+	c.setBytesLabel(name, value)
+	return c
+}
+
+func (c *counter) WithDurationLabel(name string, value time.Duration) CounterChain {
+	c.setDurationLabel(name, value)
+	return c
+}
+
+func (c *counter) WithContext(ctx context.Context) CounterChain {
+	c.setContextLabels(ctx)
+	return c
+}
+
+func (c *counter) WithStructLabels(v any) CounterChain {
+	c.setStructLabels(v)
+	return c
+}
+
 func (c *counter) Add(value int) {
 	if s := c.indirectSet(); s != nil {
 		defer s.releaseCounter(c)
-		s.getCounter(c.commit()).Add(value)
+		if m := s.getCounter(&c.builder); m != nil {
+			m.Add(value)
+		}
 	}
 }
 
 func (c *counter) AddInt64(value int64) {
 	if s := c.indirectSet(); s != nil {
 		defer s.releaseCounter(c)
-		s.getCounter(c.commit()).AddInt64(value)
+		if m := s.getCounter(&c.builder); m != nil {
+			m.AddInt64(value)
+		}
 	}
 }
 
 func (c *counter) Set(value uint64) {
 	if s := c.indirectSet(); s != nil {
 		defer s.releaseCounter(c)
-		s.getCounter(c.commit()).Set(value)
+		if m := s.getCounter(&c.builder); m != nil {
+			m.Set(value)
+		}
 	}
 }
 
 func (c *counter) Inc() {
 	if s := c.indirectSet(); s != nil {
 		defer s.releaseCounter(c)
-		s.getCounter(c.commit()).Inc()
+		if m := s.getCounter(&c.builder); m != nil {
+			m.Inc()
+		}
 	}
 }
 
 func (c *counter) Get() uint64 {
 	if s := c.indirectSet(); s != nil {
 		defer s.releaseCounter(c)
-		return s.getCounter(c.commit()).Get()
+		if m := s.getCounter(&c.builder); m != nil {
+			return m.Get()
+		}
 	}
 	return 0
 }
@@ -77,12 +142,33 @@ func (c *counter) Get() uint64 {
 func (c *counter) Dec() {
 	if s := c.indirectSet(); s != nil {
 		defer s.releaseCounter(c)
-		s.getCounter(c.commit()).Dec()
+		if m := s.getCounter(&c.builder); m != nil {
+			m.Dec()
+		}
+	}
+}
+
+func (c *counter) Unregister() bool {
+	if s := c.indirectSet(); s != nil {
+		defer s.releaseCounter(c)
+		return s.unregister(&s.cst, &c.builder)
+	}
+	return false
+}
+
+func (c *counter) Bind() *CounterHandle {
+	var hd CounterHandle
+	if s := c.indirectSet(); s != nil {
+		defer s.releaseCounter(c)
+		hd.init(s, &s.cst, &c.builder, func(fullName string) any {
+			return s.be.Counter(fullName)
+		})
 	}
+	return &hd
 }
 
 func (c *counter) indirectSet() *chain {
-	if c.sptr == 0 {
+	if c.sptr == 0 || c.raw {
 		return nil
 	}
 	return (*chain)(indirect.ToUnsafePtr(c.sptr))
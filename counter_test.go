@@ -3,7 +3,9 @@ package vmchain
 import (
 	"math"
 	"testing"
+	"time"
 
+	"github.com/VictoriaMetrics/metrics"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -53,6 +55,29 @@ func TestCounter(t *testing.T) {
 		v := cfn().Get()
 		assert.Equal(t, uint64(5), v)
 	})
+	t.Run("typed labels", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.Counter("requests_total").
+			WithIntLabel("code", 200).
+			WithUintLabel("shard", 3).
+			WithFloatLabel("ratio", 0.25, 'f', 2).
+			WithBoolLabel("cached", false).
+			WithBytesLabel("path", []byte("/users")).
+			WithDurationLabel("timeout", 30*time.Second).
+			Inc()
+		assert.Equal(t, []string{
+			`requests_total{code="200",shard="3",ratio="0.25",cached="false",path="/users",timeout="30s"}`,
+		}, set.ListMetricNames())
+	})
+	t.Run("unregister", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.Counter("tenant_requests_total").WithLabel("tenant", "a").Inc()
+		assert.True(t, c.Counter("tenant_requests_total").WithLabel("tenant", "a").Unregister())
+		assert.False(t, c.Counter("tenant_requests_total").WithLabel("tenant", "a").Unregister())
+		assert.Empty(t, set.ListMetricNames())
+	})
 }
 
 func BenchmarkCounter(b *testing.B) {
@@ -84,6 +109,27 @@ func BenchmarkCounter(b *testing.B) {
 	})
 }
 
+func BenchmarkCounterTypedLabels(b *testing.B) {
+	b.Run("any label", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Counter("myservice_typed_counter").
+				WithAnyLabel("groupID", 100000+i%10).
+				WithAnyLabel("ratio", 0.5).
+				Inc()
+		}
+	})
+	b.Run("typed label", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Counter("myservice_typed_counter").
+				WithIntLabel("groupID", int64(100000+i%10)).
+				WithFloatLabel("ratio", 0.5, 'f', -1).
+				Inc()
+		}
+	})
+}
+
 func BenchmarkCounterParallel(b *testing.B) {
 	b.Run("add", func(b *testing.B) {
 		b.ReportAllocs()
@@ -1,5 +1,7 @@
 package vmchain
 
+import "time"
+
 var defaultChain = NewChain()
 
 // Gauge return existing or create and return new gauge metric.
@@ -61,3 +63,59 @@ func FloatCounter(initName string) FloatCounterChain {
 func Histogram(initName string) HistogramChain {
 	return defaultChain.Histogram(initName)
 }
+
+// Summary return existing or create and return new summary metric.
+//
+// initName is a base name of a metric (without any labels). It must be valid Prometheus-compatible name.
+// Labels can be added separately using WithLabel chain method:
+//
+// vmchain.Summary("my_summary_metric_name").	// prepare and return metric with name "my_summary_metric_name"
+//
+//	WithLabel("stage", "area").			// add a label, so metric name became "my_summary_metric_name{stage="area"}
+//	WithLabel("userID", "123).			// add a label, so metric name became "my_summary_metric_name{stage="area",userID="123"}
+//	Update(3.14)						// finally construct full name of underlying summary metric, register it if necessary,
+//										// and call method Update.
+func Summary(initName string) SummaryChain {
+	return defaultChain.Summary(initName)
+}
+
+// SummaryExt return existing or create and return new summary metric with given window and quantiles.
+//
+// See Summary for details.
+func SummaryExt(initName string, window time.Duration, quantiles []float64) SummaryChain {
+	return defaultChain.SummaryExt(initName, window, quantiles)
+}
+
+// PrometheusHistogram return existing or create and return new Prometheus-style histogram metric with default buckets.
+//
+// initName is a base name of a metric (without any labels). It must be valid Prometheus-compatible name.
+// Labels can be added separately using WithLabel chain method:
+//
+// vmchain.PrometheusHistogram("my_phistogram_metric_name").	// prepare and return metric with name "my_phistogram_metric_name"
+//
+//	WithLabel("stage", "area").			// add a label, so metric name became "my_phistogram_metric_name{stage="area"}
+//	WithLabel("userID", "123).			// add a label, so metric name became "my_phistogram_metric_name{stage="area",userID="123"}
+//	Update(0.25)						// finally construct full name of underlying histogram metric, register it if necessary,
+//										// and call method Update.
+func PrometheusHistogram(initName string) PrometheusHistogramChain {
+	return defaultChain.PrometheusHistogram(initName)
+}
+
+// PrometheusHistogramExt return existing or create and return new Prometheus-style histogram metric with given buckets
+// upper bounds.
+//
+// See PrometheusHistogram for details.
+func PrometheusHistogramExt(initName string, upperBounds []float64) PrometheusHistogramChain {
+	return defaultChain.PrometheusHistogramExt(initName, upperBounds)
+}
+
+// NewTemplate makes a prototype of metric chains with initName and fixed labels.
+//
+// vmchain.NewTemplate("http_requests_total", "route", "/users", "method", "GET"). // build prefix once
+//
+//	Counter().						// take the chain started with http_requests_total{route="/users",method="GET"
+//	WithAnyLabel("status", 200).	// add varying label
+//	Inc()
+func NewTemplate(initName string, labels ...string) *Template {
+	return defaultChain.Template(initName, labels...)
+}
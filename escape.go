@@ -0,0 +1,71 @@
+package vmchain
+
+// EscapeMode defines how label values containing special characters are handled.
+//
+// Special characters are backslash, double-quote and line feed, see
+// https://prometheus.io/docs/instrumenting/exposition_formats/#text-format-details
+type EscapeMode uint8
+
+const (
+	// EscapeModeEscape escapes special characters according Prometheus text format (default).
+	EscapeModeEscape EscapeMode = iota
+	// EscapeModeReplace replaces each special character with underscore.
+	EscapeModeReplace
+	// EscapeModeReject rejects the metric if any label value contains special characters. Terminal methods of such
+	// chain do nothing.
+	EscapeModeReject
+)
+
+// escapeTail processes special characters in buf[off:] according mode.
+//
+// Escaping works in-place, buffer grows only if necessary. Returns false if mode is EscapeModeReject and the tail
+// contains any special character.
+func escapeTail(buf []byte, off int, mode EscapeMode) ([]byte, bool) {
+	var n int
+	for i := off; i < len(buf); i++ {
+		if isSpecial(buf[i]) {
+			n++
+		}
+	}
+	if n == 0 {
+		return buf, true
+	}
+
+	switch mode {
+	case EscapeModeReject:
+		return buf, false
+	case EscapeModeReplace:
+		for i := off; i < len(buf); i++ {
+			if isSpecial(buf[i]) {
+				buf[i] = '_'
+			}
+		}
+		return buf, true
+	}
+
+	// Each special character turns to two bytes, so grow the buffer and move bytes from the end.
+	l := len(buf)
+	for i := 0; i < n; i++ {
+		buf = append(buf, 0)
+	}
+	j := len(buf) - 1
+	for i := l - 1; i >= off; i-- {
+		c := buf[i]
+		if !isSpecial(c) {
+			buf[j] = c
+			j--
+			continue
+		}
+		if c == '\n' {
+			c = 'n'
+		}
+		buf[j] = c
+		buf[j-1] = '\\'
+		j -= 2
+	}
+	return buf, true
+}
+
+func isSpecial(c byte) bool {
+	return c == '\\' || c == '"' || c == '\n'
+}
@@ -1,16 +1,31 @@
 package vmchain
 
-import "github.com/koykov/indirect"
+import (
+	"context"
+	"time"
+
+	"github.com/koykov/indirect"
+)
 
 type FloatCounterChain interface {
 	WithLabel(name, value string) FloatCounterChain
 	L(name, value string) FloatCounterChain
 	WithAnyLabel(name string, value any) FloatCounterChain
 	AL(name string, value any) FloatCounterChain
+	WithIntLabel(name string, value int64) FloatCounterChain
+	WithUintLabel(name string, value uint64) FloatCounterChain
+	WithFloatLabel(name string, value float64, format byte, prec int) FloatCounterChain
+	WithBoolLabel(name string, value bool) FloatCounterChain
+	WithBytesLabel(name string, value []byte) FloatCounterChain
+	WithDurationLabel(name string, value time.Duration) FloatCounterChain
+	WithContext(ctx context.Context) FloatCounterChain
+	WithStructLabels(v any) FloatCounterChain
 	Add(value float64)
 	Sub(value float64)
 	Set(value float64)
 	Get() float64
+	Unregister() bool
+	Bind() *FloatCounterHandle
 }
 
 type fcounter struct {
@@ -36,37 +51,104 @@ func (c *fcounter) AL(name string, value any) FloatCounterChain {
 	return c.WithAnyLabel(name, value)
 }
 
+func (c *fcounter) WithIntLabel(name string, value int64) FloatCounterChain {
+	c.setIntLabel(name, value)
+	return c
+}
+
+func (c *fcounter) WithUintLabel(name string, value uint64) FloatCounterChain {
+	c.setUintLabel(name, value)
+	return c
+}
+
+func (c *fcounter) WithFloatLabel(name string, value float64, format byte, prec int) FloatCounterChain {
+	c.setFloatLabel(name, value, format, prec)
+	return c
+}
+
+func (c *fcounter) WithBoolLabel(name string, value bool) FloatCounterChain {
+	c.setBoolLabel(name, value)
+	return c
+}
+
+func (c *fcounter) WithBytesLabel(name string, value []byte) FloatCounterChain {
+	c.setBytesLabel(name, value)
+	return c
+}
+
+func (c *fcounter) WithDurationLabel(name string, value time.Duration) FloatCounterChain {
+	c.setDurationLabel(name, value)
+	return c
+}
+
+func (c *fcounter) WithContext(ctx context.Context) FloatCounterChain {
+	c.setContextLabels(ctx)
+	return c
+}
+
+func (c *fcounter) WithStructLabels(v any) FloatCounterChain {
+	c.setStructLabels(v)
+	return c
+}
+
 func (c *fcounter) Add(value float64) {
 	if s := c.indirectSet(); s != nil {
 		defer s.releaseFCounter(c)
-		s.getFCounter(c.commit()).Add(value)
+		if m := s.getFCounter(&c.builder); m != nil {
+			m.Add(value)
+		}
 	}
 }
 
 func (c *fcounter) Sub(value float64) {
 	if s := c.indirectSet(); s != nil {
 		defer s.releaseFCounter(c)
-		s.getFCounter(c.commit()).Sub(value)
+		if m := s.getFCounter(&c.builder); m != nil {
+			m.Sub(value)
+		}
 	}
 }
 
 func (c *fcounter) Set(value float64) {
 	if s := c.indirectSet(); s != nil {
 		defer s.releaseFCounter(c)
-		s.getFCounter(c.commit()).Set(value)
+		if m := s.getFCounter(&c.builder); m != nil {
+			m.Set(value)
+		}
 	}
 }
 
 func (c *fcounter) Get() float64 {
 	if s := c.indirectSet(); s != nil {
 		defer s.releaseFCounter(c)
-		return s.getFCounter(c.commit()).Get()
+		if m := s.getFCounter(&c.builder); m != nil {
+			return m.Get()
+		}
 	}
 	return 0
 }
 
+func (c *fcounter) Unregister() bool {
+	if s := c.indirectSet(); s != nil {
+		defer s.releaseFCounter(c)
+		return s.unregister(&s.fst, &c.builder)
+	}
+	return false
+}
+
+func (c *fcounter) Bind() *FloatCounterHandle {
+	var hd FloatCounterHandle
+	if s := c.indirectSet(); s != nil {
+		defer s.releaseFCounter(c)
+		hd.init(s, &s.fst, &c.builder, func(fullName string) any {
+			return s.be.FloatCounter(fullName)
+		})
+	}
+	return &hd
+}
+
 func (c *fcounter) indirectSet() *chain {
-	if c.sptr == 0 {
+	if c.sptr == 0 || c.raw {
 		return nil
 	}
 	return (*chain)(indirect.ToUnsafePtr(c.sptr))
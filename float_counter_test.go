@@ -3,6 +3,7 @@ package vmchain
 import (
 	"testing"
 
+	"github.com/VictoriaMetrics/metrics"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -32,6 +33,14 @@ func TestFloatCounter(t *testing.T) {
 		v := ffn().Get()
 		assert.Equal(t, 3.14, v)
 	})
+	t.Run("unregister", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.FloatCounter("tenant_bytes_total").WithLabel("tenant", "a").Add(1.5)
+		assert.True(t, c.FloatCounter("tenant_bytes_total").WithLabel("tenant", "a").Unregister())
+		assert.False(t, c.FloatCounter("tenant_bytes_total").WithLabel("tenant", "a").Unregister())
+		assert.Empty(t, set.ListMetricNames())
+	})
 }
 
 func BenchmarkFloatCounter(b *testing.B) {
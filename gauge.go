@@ -1,17 +1,32 @@
 package vmchain
 
-import "github.com/koykov/indirect"
+import (
+	"context"
+	"time"
+
+	"github.com/koykov/indirect"
+)
 
 type GaugeChain interface {
 	WithLabel(name, value string) GaugeChain
 	L(name, value string) GaugeChain
 	WithAnyLabel(name string, value any) GaugeChain
 	AL(name string, value any) GaugeChain
+	WithIntLabel(name string, value int64) GaugeChain
+	WithUintLabel(name string, value uint64) GaugeChain
+	WithFloatLabel(name string, value float64, format byte, prec int) GaugeChain
+	WithBoolLabel(name string, value bool) GaugeChain
+	WithBytesLabel(name string, value []byte) GaugeChain
+	WithDurationLabel(name string, value time.Duration) GaugeChain
+	WithContext(ctx context.Context) GaugeChain
+	WithStructLabels(v any) GaugeChain
 	Add(value float64)
 	Set(value float64)
 	Inc()
 	Get() float64
 	Dec()
+	Unregister() bool
+	Bind() *GaugeHandle
 }
 
 type gauge struct {
@@ -38,31 +53,79 @@ func (g *gauge) AL(name string, value any) GaugeChain {
 	return g.WithAnyLabel(name, value)
 }
 
+func (g *gauge) WithIntLabel(name string, value int64) GaugeChain {
+	g.setIntLabel(name, value)
+	return g
+}
+
+func (g *gauge) WithUintLabel(name string, value uint64) GaugeChain {
+	g.setUintLabel(name, value)
+	return g
+}
+
+func (g *gauge) WithFloatLabel(name string, value float64, format byte, prec int) GaugeChain {
+	g.setFloatLabel(name, value, format, prec)
+	return g
+}
+
+func (g *gauge) WithBoolLabel(name string, value bool) GaugeChain {
+	g.setBoolLabel(name, value)
+	return g
+}
+
+func (g *gauge) WithBytesLabel(name string, value []byte) GaugeChain {
+	g.setBytesLabel(name, value)
+	return g
+}
+
+func (g *gauge) WithDurationLabel(name string, value time.Duration) GaugeChain {
+	g.setDurationLabel(name, value)
+	return g
+}
+
+func (g *gauge) WithContext(ctx context.Context) GaugeChain {
+	g.setContextLabels(ctx)
+	return g
+}
+
+func (g *gauge) WithStructLabels(v any) GaugeChain {
+	g.setStructLabels(v)
+	return g
+}
+
 func (g *gauge) Add(value float64) {
 	if s := g.indirectSet(); s != nil {
 		defer s.releaseGauge(g)
-		s.getGauge(g.commit(), g.f).Add(value)
+		if m := s.getGauge(&g.builder, g.f); m != nil {
+			m.Add(value)
+		}
 	}
 }
 
 func (g *gauge) Set(value float64) {
 	if s := g.indirectSet(); s != nil {
 		defer s.releaseGauge(g)
-		s.getGauge(g.commit(), g.f).Set(value)
+		if m := s.getGauge(&g.builder, g.f); m != nil {
+			m.Set(value)
+		}
 	}
 }
 
 func (g *gauge) Inc() {
 	if s := g.indirectSet(); s != nil {
 		defer s.releaseGauge(g)
-		s.getGauge(g.commit(), g.f).Inc()
+		if m := s.getGauge(&g.builder, g.f); m != nil {
+			m.Inc()
+		}
 	}
 }
 
 func (g *gauge) Get() float64 {
 	if s := g.indirectSet(); s != nil {
 		defer s.releaseGauge(g)
-		return s.getGauge(g.commit(), g.f).Get()
+		if m := s.getGauge(&g.builder, g.f); m != nil {
+			return m.Get()
+		}
 	}
 	return 0
 }
@@ -70,12 +133,34 @@ func (g *gauge) Get() float64 {
 func (g *gauge) Dec() {
 	if s := g.indirectSet(); s != nil {
 		defer s.releaseGauge(g)
-		s.getGauge(g.commit(), g.f).Dec()
+		if m := s.getGauge(&g.builder, g.f); m != nil {
+			m.Dec()
+		}
+	}
+}
+
+func (g *gauge) Unregister() bool {
+	if s := g.indirectSet(); s != nil {
+		defer s.releaseGauge(g)
+		return s.unregister(&s.gst, &g.builder)
+	}
+	return false
+}
+
+func (g *gauge) Bind() *GaugeHandle {
+	var hd GaugeHandle
+	if s := g.indirectSet(); s != nil {
+		defer s.releaseGauge(g)
+		f := g.f
+		hd.init(s, &s.gst, &g.builder, func(fullName string) any {
+			return s.be.Gauge(fullName, f)
+		})
 	}
+	return &hd
 }
 
 func (g *gauge) indirectSet() *chain {
-	if g.sptr == 0 {
+	if g.sptr == 0 || g.raw {
 		return nil
 	}
 	return (*chain)(indirect.ToUnsafePtr(g.sptr))
@@ -4,6 +4,7 @@ import (
 	"math"
 	"testing"
 
+	"github.com/VictoriaMetrics/metrics"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -45,6 +46,14 @@ func TestGauge(t *testing.T) {
 		v := gfn().Get()
 		assert.Equal(t, float64(5), v)
 	})
+	t.Run("unregister", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.Gauge("tenant_sessions", nil).WithLabel("tenant", "a").Set(1)
+		assert.True(t, c.Gauge("tenant_sessions", nil).WithLabel("tenant", "a").Unregister())
+		assert.False(t, c.Gauge("tenant_sessions", nil).WithLabel("tenant", "a").Unregister())
+		assert.Empty(t, set.ListMetricNames())
+	})
 }
 
 func BenchmarkGauge(b *testing.B) {
@@ -0,0 +1,306 @@
+package vmchain
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// handle is a base of bound metrics.
+//
+// Handle keeps resolved metric and calls it directly, without building the name and index lookup. If the metric was
+// unregistered or evicted by TTL, handle registers it again on the next use. Handle folded into the overflow series
+// (see LimitModeOverflow) moves to its own series once the family has room for it.
+type handle struct {
+	c   *chain
+	st  *storage
+	fn  func(fullName string) any
+	mux sync.Mutex
+	b   builder
+	e   atomic.Pointer[entry]
+}
+
+func (h *handle) init(c *chain, st *storage, b *builder, fn func(fullName string) any) {
+	h.c, h.st, h.fn = c, st, fn
+	b.commit()
+	b.clone(&h.b)
+	h.resolve()
+}
+
+// entry returns actual entry of the handle.
+func (h *handle) entry() *entry {
+	if h.c == nil || h.c.disabled() {
+		return nil
+	}
+	if e := h.e.Load(); h.valid(e) {
+		h.c.touch(e)
+		return e
+	}
+	return h.resolve()
+}
+
+func (h *handle) resolve() *entry {
+	if h.c == nil {
+		return nil
+	}
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if e := h.e.Load(); h.valid(e) {
+		return e
+	}
+	if !h.b.ok() {
+		return nil
+	}
+	e := h.c.lookup(h.st, h.b.commit())
+	if e == nil {
+		// Register using a copy, since overflow rewrites the name in builder.
+		var b builder
+		h.b.clone(&b)
+		e = h.c.register(h.st, &b, h.fn)
+	}
+	h.e.Store(e)
+	return e
+}
+
+// valid checks if resolved entry e may be used. Overflow entry is valid until its family has no room.
+func (h *handle) valid(e *entry) bool {
+	return e != nil && !e.dead.Load() && (!e.ovf || e.fam.full())
+}
+
+// Name returns full name of the metric.
+func (h *handle) Name() string {
+	if h.c == nil || !h.b.ok() {
+		return ""
+	}
+	return h.b.commit()
+}
+
+// Unregister removes the metric from the chain and backend.
+// Next use of the handle will register the metric again.
+func (h *handle) Unregister() bool {
+	if h.c == nil || !h.b.ok() {
+		return false
+	}
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	return h.c.unregisterName(h.st, h.b.commit())
+}
+
+// CounterHandle is a bound counter metric. See CounterChain.Bind.
+type CounterHandle struct {
+	handle
+}
+
+func (h *CounterHandle) Add(value int) {
+	if m := h.metric(); m != nil {
+		m.Add(value)
+	}
+}
+
+func (h *CounterHandle) AddInt64(value int64) {
+	if m := h.metric(); m != nil {
+		m.AddInt64(value)
+	}
+}
+
+func (h *CounterHandle) Set(value uint64) {
+	if m := h.metric(); m != nil {
+		m.Set(value)
+	}
+}
+
+func (h *CounterHandle) Inc() {
+	if m := h.metric(); m != nil {
+		m.Inc()
+	}
+}
+
+func (h *CounterHandle) Get() uint64 {
+	if m := h.metric(); m != nil {
+		return m.Get()
+	}
+	return 0
+}
+
+func (h *CounterHandle) Dec() {
+	if m := h.metric(); m != nil {
+		m.Dec()
+	}
+}
+
+func (h *CounterHandle) metric() CounterMetric {
+	if e := h.entry(); e != nil {
+		return e.m.(CounterMetric)
+	}
+	return nil
+}
+
+// FloatCounterHandle is a bound float counter metric. See FloatCounterChain.Bind.
+type FloatCounterHandle struct {
+	handle
+}
+
+func (h *FloatCounterHandle) Add(value float64) {
+	if m := h.metric(); m != nil {
+		m.Add(value)
+	}
+}
+
+func (h *FloatCounterHandle) Sub(value float64) {
+	if m := h.metric(); m != nil {
+		m.Sub(value)
+	}
+}
+
+func (h *FloatCounterHandle) Set(value float64) {
+	if m := h.metric(); m != nil {
+		m.Set(value)
+	}
+}
+
+func (h *FloatCounterHandle) Get() float64 {
+	if m := h.metric(); m != nil {
+		return m.Get()
+	}
+	return 0
+}
+
+func (h *FloatCounterHandle) metric() FloatCounterMetric {
+	if e := h.entry(); e != nil {
+		return e.m.(FloatCounterMetric)
+	}
+	return nil
+}
+
+// GaugeHandle is a bound gauge metric. See GaugeChain.Bind.
+type GaugeHandle struct {
+	handle
+}
+
+func (h *GaugeHandle) Add(value float64) {
+	if m := h.metric(); m != nil {
+		m.Add(value)
+	}
+}
+
+func (h *GaugeHandle) Set(value float64) {
+	if m := h.metric(); m != nil {
+		m.Set(value)
+	}
+}
+
+func (h *GaugeHandle) Inc() {
+	if m := h.metric(); m != nil {
+		m.Inc()
+	}
+}
+
+func (h *GaugeHandle) Get() float64 {
+	if m := h.metric(); m != nil {
+		return m.Get()
+	}
+	return 0
+}
+
+func (h *GaugeHandle) Dec() {
+	if m := h.metric(); m != nil {
+		m.Dec()
+	}
+}
+
+func (h *GaugeHandle) metric() GaugeMetric {
+	if e := h.entry(); e != nil {
+		return e.m.(GaugeMetric)
+	}
+	return nil
+}
+
+// HistogramHandle is a bound histogram metric. See HistogramChain.Bind.
+type HistogramHandle struct {
+	handle
+}
+
+func (h *HistogramHandle) Update(value float64) {
+	if m := h.metric(); m != nil {
+		m.Update(value)
+	}
+}
+
+func (h *HistogramHandle) UpdateDuration(startTime time.Time) {
+	if m := h.metric(); m != nil {
+		m.UpdateDuration(startTime)
+	}
+}
+
+func (h *HistogramHandle) VisitNonZeroBuckets(f func(vmrange string, count uint64)) {
+	if m := h.metric(); m != nil {
+		m.VisitNonZeroBuckets(f)
+	}
+}
+
+func (h *HistogramHandle) Reset() {
+	if m := h.metric(); m != nil {
+		m.Reset()
+	}
+}
+
+func (h *HistogramHandle) metric() HistogramMetric {
+	if e := h.entry(); e != nil {
+		return e.m.(HistogramMetric)
+	}
+	return nil
+}
+
+// SummaryHandle is a bound summary metric. See SummaryChain.Bind.
+type SummaryHandle struct {
+	handle
+}
+
+func (h *SummaryHandle) Update(value float64) {
+	if m := h.metric(); m != nil {
+		m.Update(value)
+	}
+}
+
+func (h *SummaryHandle) UpdateDuration(startTime time.Time) {
+	if m := h.metric(); m != nil {
+		m.UpdateDuration(startTime)
+	}
+}
+
+func (h *SummaryHandle) metric() SummaryMetric {
+	if e := h.entry(); e != nil {
+		return e.m.(SummaryMetric)
+	}
+	return nil
+}
+
+// PrometheusHistogramHandle is a bound Prometheus-style histogram metric. See PrometheusHistogramChain.Bind.
+type PrometheusHistogramHandle struct {
+	handle
+}
+
+func (h *PrometheusHistogramHandle) Update(value float64) {
+	if m := h.metric(); m != nil {
+		m.Update(value)
+	}
+}
+
+func (h *PrometheusHistogramHandle) UpdateDuration(startTime time.Time) {
+	if m := h.metric(); m != nil {
+		m.UpdateDuration(startTime)
+	}
+}
+
+func (h *PrometheusHistogramHandle) Reset() {
+	if m := h.metric(); m != nil {
+		m.Reset()
+	}
+}
+
+func (h *PrometheusHistogramHandle) metric() PrometheusHistogramMetric {
+	if e := h.entry(); e != nil {
+		return e.m.(PrometheusHistogramMetric)
+	}
+	return nil
+}
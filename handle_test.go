@@ -0,0 +1,120 @@
+package vmchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandle(t *testing.T) {
+	t.Run("counter", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		h := c.Counter("requests_total").WithLabel("method", "GET").Bind()
+		assert.Equal(t, `requests_total{method="GET"}`, h.Name())
+		h.Inc()
+		h.Add(10)
+		h.AddInt64(5)
+		h.Dec()
+		assert.Equal(t, uint64(15), h.Get())
+		assert.Equal(t, uint64(15), c.Counter("requests_total").WithLabel("method", "GET").Get())
+		h.Set(1)
+		assert.Equal(t, uint64(1), c.Counter("requests_total").WithLabel("method", "GET").Get())
+	})
+	t.Run("all types", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		fc := c.FloatCounter("bytes_total").L("dir", "in").Bind()
+		fc.Add(1.5)
+		fc.Sub(0.5)
+		assert.Equal(t, 1.0, fc.Get())
+		g := c.Gauge("sessions", nil).L("dir", "in").Bind()
+		g.Set(5)
+		g.Inc()
+		assert.Equal(t, 6.0, g.Get())
+		c.Histogram("latency_seconds").L("dir", "in").Bind().Update(1)
+		c.Summary("size_bytes").L("dir", "in").Bind().UpdateDuration(time.Now())
+		c.PrometheusHistogramExt("size_kb", []float64{1, 2}).L("dir", "in").Bind().Update(1)
+		assert.Equal(t, []string{
+			`bytes_total{dir="in"}`,
+			`latency_seconds{dir="in"}`,
+			`sessions{dir="in"}`,
+			`size_bytes{dir="in"}`,
+			`size_kb{dir="in"}`,
+		}, set.ListMetricNames())
+	})
+	t.Run("unregister", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		h := c.Counter("requests_total").WithLabel("tenant", "a").Bind()
+		h.Add(5)
+		assert.True(t, h.Unregister())
+		assert.Empty(t, set.ListMetricNames())
+		assert.False(t, c.Counter("requests_total").WithLabel("tenant", "a").Unregister())
+
+		// Handle registers the metric again.
+		h.Inc()
+		assert.Equal(t, uint64(1), c.Counter("requests_total").WithLabel("tenant", "a").Get())
+		assert.True(t, c.Counter("requests_total").WithLabel("tenant", "a").Unregister())
+		assert.Equal(t, uint64(0), h.Get())
+	})
+	t.Run("ttl", func(t *testing.T) {
+		c := NewChain(WithVMSet(metrics.NewSet()), WithTTL(time.Minute, 0))
+		h := c.Counter("requests_total").WithLabel("tenant", "a").Bind()
+		age(c, func(c *chain) *storage { return &c.cst }, `requests_total{tenant="a"}`, time.Hour)
+		h.Inc()
+		assert.Equal(t, 0, c.Sweep())
+	})
+	t.Run("limit", func(t *testing.T) {
+		c := NewChain(WithVMSet(metrics.NewSet()), WithCardinalityLimit(1, LimitModeDrop))
+		c.Counter("requests_total").WithLabel("tenant", "a").Inc()
+		h := c.Counter("requests_total").WithLabel("tenant", "b").Bind()
+		h.Inc()
+		assert.Equal(t, uint64(0), h.Get())
+		c.UnregisterFamily("requests_total")
+		h.Inc()
+		assert.Equal(t, uint64(1), h.Get())
+	})
+	t.Run("overflow", func(t *testing.T) {
+		c := NewChain(WithVMSet(metrics.NewSet()), WithCardinalityLimit(1, LimitModeOverflow))
+		c.Counter("requests_total").WithLabel("tenant", "a").Inc()
+		h := c.Counter("requests_total").WithLabel("tenant", "b").Bind()
+		h.Inc()
+		assert.Equal(t, `requests_total{tenant="b"}`, h.Name())
+		assert.Equal(t, uint64(1), c.Counter("requests_total").WithLabel("tenant", OverflowValue).Get())
+		assert.False(t, h.Unregister())
+
+		// Family has room now, handle moves to its own series.
+		assert.True(t, c.Counter("requests_total").WithLabel("tenant", "a").Unregister())
+		h.Inc()
+		assert.Equal(t, uint64(1), h.Get())
+		assert.Equal(t, uint64(1), c.Counter("requests_total").WithLabel("tenant", OverflowValue).Get())
+		assert.True(t, h.Unregister())
+	})
+	t.Run("zero", func(t *testing.T) {
+		var h CounterHandle
+		h.Inc()
+		assert.Equal(t, uint64(0), h.Get())
+		assert.False(t, h.Unregister())
+	})
+}
+
+func BenchmarkHandle(b *testing.B) {
+	b.Run("chain", func(b *testing.B) {
+		b.ReportAllocs()
+		c := NewChain(WithVMSet(metrics.NewSet()))
+		for i := 0; i < b.N; i++ {
+			c.Counter("requests_total").WithLabel("method", "GET").WithLabel("status", "200").Inc()
+		}
+	})
+	b.Run("handle", func(b *testing.B) {
+		b.ReportAllocs()
+		c := NewChain(WithVMSet(metrics.NewSet()))
+		h := c.Counter("requests_total").WithLabel("method", "GET").WithLabel("status", "200").Bind()
+		for i := 0; i < b.N; i++ {
+			h.Inc()
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/VictoriaMetrics/metrics"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,6 +42,14 @@ func TestHistogram(t *testing.T) {
 			assert.Equal(t, count, uint64(2))
 		})
 	})
+	t.Run("unregister", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.Histogram("tenant_latency_seconds").WithLabel("tenant", "a").Update(1)
+		assert.True(t, c.Histogram("tenant_latency_seconds").WithLabel("tenant", "a").Unregister())
+		assert.False(t, c.Histogram("tenant_latency_seconds").WithLabel("tenant", "a").Unregister())
+		assert.Empty(t, set.ListMetricNames())
+	})
 }
 
 func BenchmarkHistogram(b *testing.B) {
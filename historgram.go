@@ -1,6 +1,7 @@
 package vmchain
 
 import (
+	"context"
 	"time"
 
 	"github.com/koykov/indirect"
@@ -11,10 +12,21 @@ type HistogramChain interface {
 	L(name, value string) HistogramChain
 	WithAnyLabel(name string, value any) HistogramChain
 	AL(name string, value any) HistogramChain
+	WithIntLabel(name string, value int64) HistogramChain
+	WithUintLabel(name string, value uint64) HistogramChain
+	WithFloatLabel(name string, value float64, format byte, prec int) HistogramChain
+	WithBoolLabel(name string, value bool) HistogramChain
+	WithBytesLabel(name string, value []byte) HistogramChain
+	WithDurationLabel(name string, value time.Duration) HistogramChain
+	WithContext(ctx context.Context) HistogramChain
+	WithStructLabels(v any) HistogramChain
 	Update(value float64)
 	UpdateDuration(startTime time.Time)
 	VisitNonZeroBuckets(f func(vmrange string, count uint64))
 	Reset()
+	Start() Timer
+	Unregister() bool
+	Bind() *HistogramHandle
 }
 
 type histogram struct {
@@ -40,36 +52,107 @@ func (h *histogram) AL(name string, value any) HistogramChain {
 	return h.WithAnyLabel(name, value)
 }
 
+func (h *histogram) WithIntLabel(name string, value int64) HistogramChain {
+	h.setIntLabel(name, value)
+	return h
+}
+
+func (h *histogram) WithUintLabel(name string, value uint64) HistogramChain {
+	h.setUintLabel(name, value)
+	return h
+}
+
+func (h *histogram) WithFloatLabel(name string, value float64, format byte, prec int) HistogramChain {
+	h.setFloatLabel(name, value, format, prec)
+	return h
+}
+
+func (h *histogram) WithBoolLabel(name string, value bool) HistogramChain {
+	h.setBoolLabel(name, value)
+	return h
+}
+
+func (h *histogram) WithBytesLabel(name string, value []byte) HistogramChain {
+	h.setBytesLabel(name, value)
+	return h
+}
+
+func (h *histogram) WithDurationLabel(name string, value time.Duration) HistogramChain {
+	h.setDurationLabel(name, value)
+	return h
+}
+
+func (h *histogram) WithContext(ctx context.Context) HistogramChain {
+	h.setContextLabels(ctx)
+	return h
+}
+
+func (h *histogram) WithStructLabels(v any) HistogramChain {
+	h.setStructLabels(v)
+	return h
+}
+
 func (h *histogram) Update(value float64) {
 	if s := h.indirectSet(); s != nil {
 		defer s.releaseHistogram(h)
-		s.getHistogram(h.commit()).Update(value)
+		if m := s.getHistogram(&h.builder); m != nil {
+			m.Update(value)
+		}
 	}
 }
 
 func (h *histogram) UpdateDuration(startTime time.Time) {
 	if s := h.indirectSet(); s != nil {
 		defer s.releaseHistogram(h)
-		s.getHistogram(h.commit()).UpdateDuration(startTime)
+		if m := s.getHistogram(&h.builder); m != nil {
+			m.UpdateDuration(startTime)
+		}
 	}
 }
 
 func (h *histogram) VisitNonZeroBuckets(f func(vmrange string, count uint64)) {
 	if s := h.indirectSet(); s != nil {
 		defer s.releaseHistogram(h)
-		s.getHistogram(h.commit()).VisitNonZeroBuckets(f)
+		if m := s.getHistogram(&h.builder); m != nil {
+			m.VisitNonZeroBuckets(f)
+		}
 	}
 }
 
 func (h *histogram) Reset() {
 	if s := h.indirectSet(); s != nil {
 		defer s.releaseHistogram(h)
-		s.getHistogram(h.commit()).Reset()
+		if m := s.getHistogram(&h.builder); m != nil {
+			m.Reset()
+		}
+	}
+}
+
+func (h *histogram) Start() Timer {
+	return startTimer(h)
+}
+
+func (h *histogram) Unregister() bool {
+	if s := h.indirectSet(); s != nil {
+		defer s.releaseHistogram(h)
+		return s.unregister(&s.hst, &h.builder)
+	}
+	return false
+}
+
+func (h *histogram) Bind() *HistogramHandle {
+	var hd HistogramHandle
+	if s := h.indirectSet(); s != nil {
+		defer s.releaseHistogram(h)
+		hd.init(s, &s.hst, &h.builder, func(fullName string) any {
+			return s.be.Histogram(fullName)
+		})
 	}
+	return &hd
 }
 
 func (h *histogram) indirectSet() *chain {
-	if h.sptr == 0 {
+	if h.sptr == 0 || h.raw {
 		return nil
 	}
 	return (*chain)(indirect.ToUnsafePtr(h.sptr))
@@ -0,0 +1,228 @@
+package vmchain
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/koykov/byteconv"
+)
+
+const (
+	contentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// FamilyParam is a query parameter of HTTP handler to filter exposed metric families.
+const FamilyParam = "family"
+
+var bufPool = sync.Pool{New: func() any { return &bytes.Buffer{} }}
+
+func (c *chain) WritePrometheus(w io.Writer) {
+	c.writePrometheus(w, nil)
+}
+
+func (c *chain) Handler() http.Handler {
+	return http.HandlerFunc(c.serveHTTP)
+}
+
+func (c *chain) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var families []string
+	if r.URL != nil {
+		families = r.URL.Query()[FamilyParam]
+	}
+
+	w.Header().Set("Vary", "Accept, Accept-Encoding")
+	om := acceptOpenMetrics(r.Header.Get("Accept"))
+	if om {
+		w.Header().Set("Content-Type", contentTypeOpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", contentTypeText)
+	}
+
+	var out io.Writer = w
+	if acceptGzip(r.Header.Get("Accept-Encoding")) {
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		defer func() { _ = zw.Close() }()
+		out = zw
+	}
+
+	if om {
+		c.writeOpenMetrics(out, families)
+	} else {
+		c.writePrometheus(out, families)
+	}
+}
+
+// acceptOpenMetrics checks if Accept header prefers OpenMetrics to Prometheus text format.
+func acceptOpenMetrics(accept string) bool {
+	om := acceptQ(accept, "application/openmetrics-text")
+	text := acceptQ(accept, "text/plain")
+	if text < 0 {
+		text = acceptQ(accept, "text/*")
+	}
+	if text < 0 {
+		text = acceptQ(accept, "*/*")
+	}
+	return om > 0 && om >= text
+}
+
+// acceptGzip checks if Accept-Encoding header allows gzip compression.
+func acceptGzip(encoding string) bool {
+	q := acceptQ(encoding, "gzip")
+	if q < 0 {
+		q = acceptQ(encoding, "*")
+	}
+	return q > 0
+}
+
+// acceptQ returns the highest quality value of token in Accept or Accept-Encoding header value h. Returns -1 if h
+// doesn't mention the token.
+func acceptQ(h, token string) float64 {
+	q := -1.0
+	for len(h) > 0 {
+		var part, params string
+		part, h, _ = strings.Cut(h, ",")
+		part, params, _ = strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(part), token) {
+			continue
+		}
+		pq := 1.0
+		for len(params) > 0 {
+			var param string
+			param, params, _ = strings.Cut(params, ";")
+			k, v, _ := strings.Cut(param, "=")
+			if strings.EqualFold(strings.TrimSpace(k), "q") {
+				var err error
+				if pq, err = strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+					pq = 0
+				}
+			}
+		}
+		if pq > q {
+			q = pq
+		}
+	}
+	return q
+}
+
+// writePrometheus writes series of the chain in Prometheus text format to w.
+//
+// Since VM set may contain metrics registered bypassing the chain, output filters by metric families known to the
+// chain. If families isn't empty, output filters additionally by them.
+func (c *chain) writePrometheus(w io.Writer, families []string) {
+	buf := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(buf)
+	buf.Reset()
+	if pw, ok := c.be.(prometheusWriter); ok {
+		pw.WritePrometheus(buf)
+	}
+
+	b := buf.Bytes()
+	for len(b) > 0 {
+		var line []byte
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line, b = b[:i+1], b[i+1:]
+		} else {
+			line, b = b, nil
+		}
+		if c.exposed(lineFamily(line), families) {
+			_, _ = w.Write(line)
+		}
+	}
+}
+
+// writeOpenMetrics writes series of the chain in OpenMetrics format to w.
+//
+// Metadata of VM set doesn't follow OpenMetrics rules (e.g. counters without _total suffix or histograms with vmrange
+// buckets), so it's dropped and all metrics are exposed with unknown type. Since OpenMetrics requires contiguous
+// samples of each metric, lines are grouped by metric name.
+func (c *chain) writeOpenMetrics(w io.Writer, families []string) {
+	buf := bufPool.Get().(*bytes.Buffer)
+	defer bufPool.Put(buf)
+	buf.Reset()
+	c.writePrometheus(buf, families)
+
+	var lines [][]byte
+	order := make(map[string]int)
+	b := buf.Bytes()
+	for len(b) > 0 {
+		var line []byte
+		if i := bytes.IndexByte(b, '\n'); i >= 0 {
+			line, b = b[:i+1], b[i+1:]
+		} else {
+			line, b = append(b, '\n'), nil
+		}
+		if line[0] == '#' {
+			continue
+		}
+		if name := lineFamily(line); len(name) > 0 {
+			if _, ok := order[string(name)]; !ok {
+				order[string(name)] = len(order)
+			}
+			lines = append(lines, line)
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return order[string(lineFamily(lines[i]))] < order[string(lineFamily(lines[j]))]
+	})
+	for _, line := range lines {
+		_, _ = w.Write(line)
+	}
+	_, _ = io.WriteString(w, "# EOF\n")
+}
+
+// exposed checks if metric with given name belongs to families of the chain or its sub-chains and matches families
+// filter.
+//
+// Limiter is shared with parent chain, so family is checked by the chain registered it.
+func (c *chain) exposed(name []byte, families []string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	family := name
+	if !c.owns(c.lim.owner(family)) {
+		family = trimSuffix(family)
+		if !c.owns(c.lim.owner(family)) {
+			return false
+		}
+	}
+	if len(families) == 0 {
+		return true
+	}
+	for i := 0; i < len(families); i++ {
+		if byteconv.B2S(family) == families[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// lineFamily returns metric name of exposition line, including metadata lines.
+func lineFamily(line []byte) []byte {
+	if bytes.HasPrefix(line, []byte("# HELP ")) || bytes.HasPrefix(line, []byte("# TYPE ")) {
+		line = line[7:]
+	} else if len(line) > 0 && line[0] == '#' {
+		return nil
+	}
+	if i := bytes.IndexAny(line, "{ \n"); i >= 0 {
+		line = line[:i]
+	}
+	return line
+}
+
+// trimSuffix removes suffixes of auxiliary series of histograms and summaries.
+func trimSuffix(name []byte) []byte {
+	for _, sfx := range [...]string{"_bucket", "_sum", "_count"} {
+		if bytes.HasSuffix(name, byteconv.S2B(sfx)) {
+			return name[:len(name)-len(sfx)]
+		}
+	}
+	return name
+}
@@ -0,0 +1,165 @@
+package vmchain
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	newServer := func() *httptest.Server {
+		set := metrics.NewSet()
+		set.NewCounter("foreign_total").Inc()
+		c := NewChain(WithVMSet(set))
+		c.Counter("requests_total").WithLabel("method", "GET").Add(2)
+		c.Gauge("sessions", nil).Set(3)
+		c.PrometheusHistogramExt("size_kb", []float64{1}).Update(0.5)
+		return httptest.NewServer(c.Handler())
+	}
+	get := func(t *testing.T, url string, hdr map[string]string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		for k, v := range hdr {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var r io.Reader = resp.Body
+		if resp.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(resp.Body)
+			assert.NoError(t, err)
+			r = zr
+		}
+		body, _ := io.ReadAll(r)
+		return resp, string(body)
+	}
+
+	t.Run("text", func(t *testing.T) {
+		srv := newServer()
+		defer srv.Close()
+		resp, body := get(t, srv.URL, nil)
+		assert.Equal(t, contentTypeText, resp.Header.Get("Content-Type"))
+		assert.Equal(t, `requests_total{method="GET"} 2
+sessions 3
+size_kb_bucket{le="1"} 1
+size_kb_bucket{le="+Inf"} 1
+size_kb_sum 0.5
+size_kb_count 1
+`, body)
+	})
+	t.Run("openmetrics", func(t *testing.T) {
+		srv := newServer()
+		defer srv.Close()
+		resp, body := get(t, srv.URL+"?family=sessions", map[string]string{
+			"Accept": "application/openmetrics-text; version=1.0.0",
+		})
+		assert.Equal(t, contentTypeOpenMetrics, resp.Header.Get("Content-Type"))
+		assert.Equal(t, "Accept, Accept-Encoding", resp.Header.Get("Vary"))
+		assert.Equal(t, "sessions 3\n# EOF\n", body)
+	})
+	t.Run("openmetrics metadata", func(t *testing.T) {
+		metrics.ExposeMetadata(true)
+		defer metrics.ExposeMetadata(false)
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.Counter("requests").WithLabel("method", "GET").Inc()
+		c.Counter("requests").WithLabel("method", "POST").Inc()
+		c.PrometheusHistogramExt("size_kb", []float64{1}).WithLabel("dir", "in").Update(0.5)
+		c.PrometheusHistogramExt("size_kb", []float64{1}).WithLabel("dir", "out").Update(2)
+		srv := httptest.NewServer(c.Handler())
+		defer srv.Close()
+		_, body := get(t, srv.URL, map[string]string{
+			"Accept": "application/openmetrics-text;version=1.0.0;q=0.5,text/plain;version=0.0.4;q=0.3,*/*;q=0.2",
+		})
+		assert.Equal(t, `requests{method="GET"} 1
+requests{method="POST"} 1
+size_kb_bucket{dir="in",le="1"} 1
+size_kb_bucket{dir="in",le="+Inf"} 1
+size_kb_bucket{dir="out",le="1"} 0
+size_kb_bucket{dir="out",le="+Inf"} 1
+size_kb_sum{dir="in"} 0.5
+size_kb_sum{dir="out"} 2
+size_kb_count{dir="in"} 1
+size_kb_count{dir="out"} 1
+# EOF
+`, body)
+	})
+	t.Run("negotiation", func(t *testing.T) {
+		srv := newServer()
+		defer srv.Close()
+		for _, hdr := range []map[string]string{
+			{"Accept": "application/openmetrics-text;q=0"},
+			{"Accept": "application/openmetrics-text;q=0.3,text/plain;q=0.5"},
+			{"Accept": "*/*", "Accept-Encoding": "gzip;q=0"},
+			{"Accept-Encoding": "*;q=0.5,gzip;q=0"},
+		} {
+			resp, body := get(t, srv.URL+"?family=sessions", hdr)
+			assert.Equal(t, contentTypeText, resp.Header.Get("Content-Type"))
+			assert.Empty(t, resp.Header.Get("Content-Encoding"))
+			assert.Equal(t, "sessions 3\n", body)
+		}
+		resp, _ := get(t, srv.URL, map[string]string{"Accept-Encoding": "deflate, *;q=0.1"})
+		assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	})
+	t.Run("gzip", func(t *testing.T) {
+		srv := newServer()
+		defer srv.Close()
+		resp, body := get(t, srv.URL+"?family=sessions&family=size_kb", map[string]string{
+			"Accept-Encoding": "gzip",
+		})
+		assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+		assert.Equal(t, `sessions 3
+size_kb_bucket{le="1"} 1
+size_kb_bucket{le="+Inf"} 1
+size_kb_sum 0.5
+size_kb_count 1
+`, body)
+	})
+	t.Run("sub", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.Counter("requests_total").Inc()
+		c.Gauge("db_size", nil).Set(1)
+		c.Sub("cache").Counter("hits_total").Inc()
+		c.Sub("db_x").Counter("hits_total").Inc()
+		db := c.Sub("db")
+		db.Counter("queries_total").Inc()
+		db.Histogram("latency_seconds").Update(1)
+		srv := httptest.NewServer(db.Handler())
+		defer srv.Close()
+		_, body := get(t, srv.URL+"?family=db_queries_total", nil)
+		assert.Equal(t, "db_queries_total 1\n", body)
+		_, body = get(t, srv.URL, nil)
+		assert.Equal(t, `db_latency_seconds_bucket{vmrange="8.799e-01...1.000e+00"} 1
+db_latency_seconds_sum 1
+db_latency_seconds_count 1
+db_queries_total 1
+`, body)
+
+		// Parent exposes series of all sub-chains.
+		psrv := httptest.NewServer(c.Handler())
+		defer psrv.Close()
+		_, body = get(t, psrv.URL+"?family=db_size&family=db_x_hits_total&family=db_queries_total", nil)
+		assert.Equal(t, `db_queries_total 1
+db_size 1
+db_x_hits_total 1
+`, body)
+	})
+	t.Run("unregister", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.Counter("requests_total").WithLabel("method", "GET").Inc()
+		c.UnregisterFamily("requests_total")
+		srv := httptest.NewServer(c.Handler())
+		defer srv.Close()
+		_, body := get(t, srv.URL, nil)
+		assert.Equal(t, "", body)
+	})
+}
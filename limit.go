@@ -0,0 +1,138 @@
+package vmchain
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// LimitMode defines what to do with new series when family's cardinality limit reached.
+type LimitMode uint8
+
+const (
+	// LimitModeDrop silently drops new series (default).
+	LimitModeDrop LimitMode = iota
+	// LimitModeOverflow folds new series into the overflow series, where all label values replaced with OverflowValue.
+	// Constant labels of the chain (see WithConstLabels) keep their values, unless overridden by the series.
+	LimitModeOverflow
+	// LimitModeReport passes ErrCardinalityLimit to error handler (see WithErrorHandler) and drops new series.
+	LimitModeReport
+)
+
+// OverflowValue is a label value of overflow series.
+const OverflowValue = "__overflow__"
+
+// overflowLimit is a max number of overflow series in the family. Family may have several overflow series if its
+// series have different sets of label names.
+const overflowLimit = 16
+
+var ErrCardinalityLimit = errors.New("cardinality limit reached")
+
+// FamilyStats represents series statistics of metric family (all series with the same initName).
+type FamilyStats struct {
+	// Series is a number of registered series.
+	Series uint64
+	// Rejected is a number of attempts to register new series over the limit.
+	Rejected uint64
+}
+
+type family struct {
+	// Chain that registered the family first.
+	owner    *chain
+	limit    uint64
+	series   atomic.Uint64
+	rejected atomic.Uint64
+	// Overflow series don't count in series, so family gets room for own series after unregistering of them.
+	overflow atomic.Uint64
+}
+
+// full checks if family has no room for new series.
+func (f *family) full() bool {
+	return f.limit > 0 && f.series.Load() >= f.limit
+}
+
+// admit tries to count new series. Returns false if limit reached.
+func (f *family) admit() bool {
+	if n := f.series.Add(1); f.limit > 0 && n > f.limit {
+		f.series.Add(^uint64(0))
+		return false
+	}
+	return true
+}
+
+// admitOverflow tries to count new overflow series. Returns false if overflowLimit reached.
+func (f *family) admitOverflow() bool {
+	if n := f.overflow.Add(1); n > overflowLimit {
+		f.overflow.Add(^uint64(0))
+		return false
+	}
+	return true
+}
+
+func (f *family) stats() FamilyStats {
+	return FamilyStats{
+		Series:   f.series.Load() + f.overflow.Load(),
+		Rejected: f.rejected.Load(),
+	}
+}
+
+// limiter keeps cardinality limits and statistics of metric families.
+type limiter struct {
+	limit uint64
+	mode  LimitMode
+	flim  map[string]uint64
+
+	mux  sync.RWMutex
+	fams map[string]*family
+}
+
+// family returns family initName. New family belongs to owner.
+func (l *limiter) family(initName []byte, owner *chain) *family {
+	// Fast check.
+	l.mux.RLock()
+	f, ok := l.fams[string(initName)]
+	l.mux.RUnlock()
+	if ok {
+		return f
+	}
+
+	// Slow path.
+	l.mux.Lock()
+	defer l.mux.Unlock()
+
+	// Double check.
+	if f, ok = l.fams[string(initName)]; ok {
+		// Double check passed.
+		return f
+	}
+
+	f = &family{owner: owner, limit: l.limit}
+	if limit, ok := l.flim[string(initName)]; ok {
+		f.limit = limit
+	}
+	if l.fams == nil {
+		l.fams = make(map[string]*family)
+	}
+	l.fams[string(initName)] = f
+	return f
+}
+
+func (l *limiter) stats(initName string) FamilyStats {
+	l.mux.RLock()
+	defer l.mux.RUnlock()
+	if f, ok := l.fams[initName]; ok {
+		return f.stats()
+	}
+	return FamilyStats{}
+}
+
+// owner returns the chain owning family initName if the family has registered series.
+func (l *limiter) owner(initName []byte) *chain {
+	l.mux.RLock()
+	f, ok := l.fams[string(initName)]
+	l.mux.RUnlock()
+	if !ok || f.series.Load()+f.overflow.Load() == 0 {
+		return nil
+	}
+	return f.owner
+}
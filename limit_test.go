@@ -0,0 +1,140 @@
+package vmchain
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimit(t *testing.T) {
+	t.Run("drop", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithCardinalityLimit(3, LimitModeDrop))
+		for i := 0; i < 10; i++ {
+			c.Counter("requests_total").WithAnyLabel("userID", i).Inc()
+		}
+		c.Counter("requests_total").WithAnyLabel("userID", 1).Inc()
+		assert.Len(t, set.ListMetricNames(), 3)
+		assert.Equal(t, uint64(2), c.Counter("requests_total").WithAnyLabel("userID", 1).Get())
+		assert.Equal(t, uint64(0), c.Counter("requests_total").WithAnyLabel("userID", 9).Get())
+		assert.Equal(t, FamilyStats{Series: 3, Rejected: 8}, c.FamilyStats("requests_total"))
+
+		// Existing series of the full family, e.g. registered concurrently.
+		cc := c.Counter("requests_total").WithAnyLabel("userID", 1).(*counter)
+		cc.commit()
+		cs := c.(*chain)
+		assert.NotNil(t, cs.register(&cs.cst, &cc.builder, func(string) any { panic("unreachable") }))
+		assert.Equal(t, FamilyStats{Series: 3, Rejected: 8}, c.FamilyStats("requests_total"))
+	})
+	t.Run("overflow", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithCardinalityLimit(2, LimitModeOverflow))
+		for i := 0; i < 5; i++ {
+			c.Counter("requests_total").
+				WithLabel("method", "GET").
+				WithAnyLabel("userID", i).
+				Inc()
+		}
+		assert.Equal(t, []string{
+			`requests_total{method="GET",userID="0"}`,
+			`requests_total{method="GET",userID="1"}`,
+			`requests_total{method="__overflow__",userID="__overflow__"}`,
+		}, set.ListMetricNames())
+		assert.Equal(t, uint64(3), c.Counter("requests_total").
+			WithLabel("method", "POST").
+			WithAnyLabel("userID", 100).
+			Get())
+		assert.Equal(t, FamilyStats{Series: 3, Rejected: 4}, c.FamilyStats("requests_total"))
+	})
+	t.Run("overflow const labels", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithCardinalityLimit(1, LimitModeOverflow), WithConstLabels("app", "x", "env", "prod"))
+		for i := 0; i < 5; i++ {
+			c.Counter("requests_total").
+				WithAnyLabel("app", "v"+strconv.Itoa(i)).
+				WithAnyLabel("u", i).
+				Inc()
+		}
+		t1 := c.Template("requests_total", "tpl", "a")
+		t2 := c.Template("requests_total", "tpl", "b")
+		t1.Counter().WithAnyLabel("u", 1).Inc()
+		t2.Counter().WithAnyLabel("u", 2).Inc()
+		assert.Equal(t, []string{
+			`requests_total{app="__overflow__",env="prod",u="__overflow__"}`,
+			`requests_total{app="v0",env="prod",u="0"}`,
+			`requests_total{app="x",env="prod",tpl="__overflow__",u="__overflow__"}`,
+		}, set.ListMetricNames())
+		assert.Equal(t, FamilyStats{Series: 3, Rejected: 6}, c.FamilyStats("requests_total"))
+	})
+	t.Run("overflow limit", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithCardinalityLimit(1, LimitModeOverflow))
+		for i := 0; i < overflowLimit*2; i++ {
+			c.Counter("requests_total").WithAnyLabel("l"+strconv.Itoa(i), i).Inc()
+		}
+		assert.Len(t, set.ListMetricNames(), 1+overflowLimit)
+		assert.Equal(t, FamilyStats{Series: 1 + overflowLimit, Rejected: overflowLimit*2 - 1}, c.FamilyStats("requests_total"))
+	})
+	t.Run("report", func(t *testing.T) {
+		var errs []error
+		c := NewChain(WithVMSet(metrics.NewSet()), WithCardinalityLimit(1, LimitModeReport), WithErrorHandler(func(err error) {
+			errs = append(errs, err)
+		}))
+		c.Histogram("latency_seconds").WithLabel("path", "/a").Update(1)
+		c.Histogram("latency_seconds").WithLabel("path", "/b").Update(1)
+		assert.Len(t, errs, 1)
+		assert.True(t, errors.Is(errs[0], ErrCardinalityLimit))
+		assert.EqualError(t, errs[0], "cardinality limit reached: latency_seconds")
+	})
+	t.Run("family limit", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithFamilyCardinalityLimit("limited_total", 1))
+		for i := 0; i < 5; i++ {
+			c.Counter("limited_total").WithAnyLabel("id", i).Inc()
+			c.Counter("unlimited_total").WithAnyLabel("id", i).Inc()
+		}
+		assert.Equal(t, FamilyStats{Series: 1, Rejected: 4}, c.FamilyStats("limited_total"))
+		assert.Equal(t, FamilyStats{Series: 5}, c.FamilyStats("unlimited_total"))
+		assert.Equal(t, FamilyStats{}, c.FamilyStats("unknown"))
+	})
+	t.Run("sub family limit", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithNamespace("api"), WithFamilyCardinalityLimit("db_queries_total", 1),
+			WithFamilyCardinalityLimit("cache_hits_total", 2))
+		db, cache := c.Sub("db"), c.Sub("cache")
+		for i := 0; i < 5; i++ {
+			db.Counter("queries_total").WithAnyLabel("id", i).Inc()
+			cache.Counter("hits_total").WithAnyLabel("id", i).Inc()
+			c.Counter("queries_total").WithAnyLabel("id", i).Inc()
+		}
+		assert.Equal(t, FamilyStats{Series: 1, Rejected: 4}, db.FamilyStats("queries_total"))
+		assert.Equal(t, FamilyStats{Series: 2, Rejected: 3}, cache.FamilyStats("hits_total"))
+		assert.Equal(t, FamilyStats{Series: 5}, c.FamilyStats("queries_total"))
+	})
+}
+
+func BenchmarkLimit(b *testing.B) {
+	b.Run("overflow", func(b *testing.B) {
+		b.ReportAllocs()
+		c := NewChain(WithVMSet(metrics.NewSet()), WithCardinalityLimit(10, LimitModeOverflow))
+		for i := 0; i < 20; i++ {
+			c.Counter("requests_total").WithLabel("userID", strconv.Itoa(i)).Inc()
+		}
+		for i := 0; i < b.N; i++ {
+			c.Counter("requests_total").WithAnyLabel("userID", i%1000).Inc()
+		}
+	})
+	b.Run("drop", func(b *testing.B) {
+		b.ReportAllocs()
+		c := NewChain(WithVMSet(metrics.NewSet()), WithCardinalityLimit(10, LimitModeDrop))
+		for i := 0; i < 20; i++ {
+			c.Counter("requests_total").WithLabel("userID", strconv.Itoa(i)).Inc()
+		}
+		for i := 0; i < b.N; i++ {
+			c.Counter("requests_total").WithAnyLabel("userID", i%1000).Inc()
+		}
+	})
+}
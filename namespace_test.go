@@ -0,0 +1,56 @@
+package vmchain
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamespace(t *testing.T) {
+	t.Run("prefix", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithNamespace("myservice"))
+		c.Counter("requests_total").WithLabel("method", "GET").Inc()
+		assert.Equal(t, []string{`myservice_requests_total{method="GET"}`}, set.ListMetricNames())
+		assert.Equal(t, FamilyStats{Series: 1}, c.FamilyStats("requests_total"))
+	})
+	t.Run("sub", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithNamespace("myservice"), WithConstLabels("region", "eu"))
+		db := c.Sub("db")
+		assert.Same(t, db, c.Sub("db"))
+		db.Counter("queries_total").Inc()
+		db.Sub("pool").Gauge("connections", nil).Set(5)
+		c.Counter("requests_total").Inc()
+		assert.Equal(t, []string{
+			`myservice_db_pool_connections{region="eu"}`,
+			`myservice_db_queries_total{region="eu"}`,
+			`myservice_requests_total{region="eu"}`,
+		}, set.ListMetricNames())
+
+		// Storage is shared with parent.
+		assert.Equal(t, uint64(1), c.Counter("db_queries_total").Get())
+		assert.Equal(t, 1, db.UnregisterFamily("queries_total"))
+		assert.Equal(t, []string{
+			`myservice_db_pool_connections{region="eu"}`,
+			`myservice_requests_total{region="eu"}`,
+		}, set.ListMetricNames())
+	})
+	t.Run("family limit", func(t *testing.T) {
+		c := NewChain(WithVMSet(metrics.NewSet()), WithNamespace("myservice"), WithFamilyCardinalityLimit("requests_total", 1))
+		c.Counter("requests_total").WithLabel("id", "1").Inc()
+		c.Counter("requests_total").WithLabel("id", "2").Inc()
+		assert.Equal(t, FamilyStats{Series: 1, Rejected: 1}, c.FamilyStats("requests_total"))
+	})
+}
+
+func BenchmarkNamespace(b *testing.B) {
+	b.Run("sub", func(b *testing.B) {
+		b.ReportAllocs()
+		c := NewChain(WithVMSet(metrics.NewSet()), WithNamespace("myservice")).Sub("db")
+		for i := 0; i < b.N; i++ {
+			c.Counter("queries_total").WithLabel("table", "users").Inc()
+		}
+	})
+}
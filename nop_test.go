@@ -0,0 +1,117 @@
+package vmchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNop(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewNopChain(WithVMSet(set))
+		assert.False(t, c.Enabled())
+		c.Gauge("sessions", nil).WithLabel("dir", "in").Set(1)
+		c.Counter("requests_total").WithLabel("method", "GET").WithIntLabel("code", 200).Inc()
+		c.FloatCounter("bytes_total").Add(1)
+		c.Histogram("latency_seconds").Update(1)
+		c.Summary("size_bytes").Start().WithLabel("status", "ok").Stop()
+		c.PrometheusHistogram("size_kb").UpdateDuration(time.Now())
+		assert.Equal(t, uint64(0), c.Counter("requests_total").WithLabel("method", "GET").Get())
+		assert.False(t, c.Counter("requests_total").WithLabel("method", "GET").Unregister())
+		assert.Empty(t, set.ListMetricNames())
+		assert.Equal(t, FamilyStats{}, c.FamilyStats("requests_total"))
+	})
+	t.Run("runtime", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		sub := c.Sub("db")
+		tpl := c.Template("requests_total", "method", "GET")
+		h := c.Counter("handled_total").Bind()
+
+		c.SetEnabled(false)
+		c.Counter("requests_total").Inc()
+		sub.Counter("queries_total").Inc()
+		tpl.Counter().Inc()
+		h.Inc()
+		// Handle was bound before disabling.
+		assert.Equal(t, []string{"handled_total"}, set.ListMetricNames())
+		assert.Equal(t, uint64(0), h.Get())
+
+		c.SetEnabled(true)
+		assert.True(t, sub.Enabled())
+		sub.Counter("queries_total").Inc()
+		tpl.Counter().Inc()
+		h.Inc()
+		assert.Equal(t, uint64(1), h.Get())
+		assert.Equal(t, []string{"db_queries_total", "handled_total", `requests_total{method="GET"}`}, set.ListMetricNames())
+	})
+	t.Run("bind", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewNopChain(WithVMSet(set), WithConstLabels("app", "api"))
+		tpl := c.Template("requests_total", "method", "GET")
+		// Labels of disabled chain aren't recorded, so handles stay no-op.
+		h := c.Counter("handled_total").WithLabel("status", "ok").WithIntLabel("code", 200).Bind()
+		th := tpl.Counter().Bind()
+		h.Inc()
+		th.Inc()
+		assert.Equal(t, "", h.Name())
+		assert.False(t, h.Unregister())
+
+		c.SetEnabled(true)
+		h.Inc()
+		th.Inc()
+		assert.Equal(t, uint64(0), h.Get())
+		assert.Equal(t, uint64(0), th.Get())
+		assert.Empty(t, set.ListMetricNames())
+
+		// Template made by disabled chain works after enabling.
+		tpl.Counter().Bind().Inc()
+		assert.Equal(t, []string{`requests_total{app="api",method="GET"}`}, set.ListMetricNames())
+	})
+	t.Run("sub", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		db, cache := c.Sub("db"), c.Sub("cache")
+
+		db.SetEnabled(false)
+		assert.True(t, c.Enabled())
+		assert.True(t, cache.Enabled())
+		assert.False(t, db.Enabled())
+		c.Counter("requests_total").Inc()
+		cache.Counter("hits_total").Inc()
+		db.Counter("queries_total").Inc()
+		assert.Equal(t, []string{"cache_hits_total", "requests_total"}, set.ListMetricNames())
+
+		// Parent switches off all sub-chains, but keeps own flags of them.
+		c.SetEnabled(false)
+		assert.False(t, cache.Enabled())
+		c.SetEnabled(true)
+		assert.True(t, cache.Enabled())
+		assert.False(t, db.Enabled())
+	})
+}
+
+func BenchmarkNop(b *testing.B) {
+	c := NewNopChain(WithVMSet(metrics.NewSet()))
+	b.Run("counter", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c.Counter("requests_total").
+				WithLabel("method", "GET").
+				WithIntLabel("code", 200).
+				Inc()
+		}
+	})
+	b.Run("timer", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			c.Histogram("latency_seconds").
+				WithLabel("method", "GET").
+				Start().
+				Stop()
+		}
+	})
+}
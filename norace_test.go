@@ -0,0 +1,5 @@
+//go:build !race
+
+package vmchain
+
+const raceEnabled = false
@@ -1,6 +1,10 @@
 package vmchain
 
-import "github.com/VictoriaMetrics/metrics"
+import (
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+)
 
 type Option func(c *chain)
 
@@ -8,6 +12,122 @@ type Option func(c *chain)
 // Caution! You must register set using metrics.RegisterSet yourself.
 func WithVMSet(vmset *metrics.Set) Option {
 	return func(c *chain) {
-		c.vmset = vmset
+		c.bnew = func() Backend { return NewVMBackend(vmset) }
+	}
+}
+
+// WithBackend sets backend to keep metrics instead of VM set. Chain.Close closes the backend if it implements
+// io.Closer.
+func WithBackend(backend Backend) Option {
+	return func(c *chain) {
+		c.bnew = func() Backend { return backend }
+	}
+}
+
+// WithStatsD makes the chain to send metrics to StatsD agent at addr (host:port) over UDP instead of VM set.
+// See NewStatsDBackend for details. Errors pass to the error handler of the chain (see WithErrorHandler) by default.
+// Call Chain.Close to make the final flush.
+func WithStatsD(addr string, options ...StatsDOption) Option {
+	return func(c *chain) {
+		c.bnew = func() Backend {
+			return NewStatsDBackend(addr, append([]StatsDOption{WithStatsDErrorHandler(c.onErr)}, options...)...)
+		}
+	}
+}
+
+// WithDisabled makes the chain disabled from the start. See Chain.SetEnabled.
+func WithDisabled() Option {
+	return func(c *chain) {
+		c.off.Store(true)
+	}
+}
+
+// WithEscapeMode sets the way to handle special characters in label values.
+// By default, special characters are escaped according Prometheus text format.
+func WithEscapeMode(mode EscapeMode) Option {
+	return func(c *chain) {
+		c.esc = mode
+	}
+}
+
+// WithValidation enables validation of metric and label names according Prometheus data model.
+func WithValidation(mode ValidationMode) Option {
+	return func(c *chain) {
+		c.val = mode
+	}
+}
+
+// WithErrorHandler sets the function to pass errors that cause metric drop (invalid names, rejected label values, ...).
+func WithErrorHandler(fn func(error)) Option {
+	return func(c *chain) {
+		c.onErr = fn
+	}
+}
+
+// WithCardinalityLimit limits number of series in each metric family (series with the same initName).
+// New series over the limit are handled according mode. Zero limit means no limit.
+func WithCardinalityLimit(limit uint64, mode LimitMode) Option {
+	return func(c *chain) {
+		c.lim.limit, c.lim.mode = limit, mode
+	}
+}
+
+// WithFamilyCardinalityLimit overrides cardinality limit for metric family initName.
+// initName is relative to the namespace of the chain (see WithNamespace). Families of sub-chains are addressed with
+// subsystem prefix, e.g. "db_queries_total" limits family "queries_total" of sub-chain Sub("db").
+func WithFamilyCardinalityLimit(initName string, limit uint64) Option {
+	return func(c *chain) {
+		if c.lim.flim == nil {
+			c.lim.flim = make(map[string]uint64)
+		}
+		c.lim.flim[initName] = limit
+	}
+}
+
+// WithTTL enables eviction of series that weren't used longer than ttl. Evicted series are removed from the chain and
+// unregistered from backend. Eviction works with seconds precision.
+//
+// If sweepInterval is greater than zero, the chain starts background sweeper, that may be stopped using Chain.Close.
+// Otherwise, call Chain.Sweep manually.
+func WithTTL(ttl, sweepInterval time.Duration) Option {
+	return func(c *chain) {
+		c.ttl, c.swi = ttl, sweepInterval
+	}
+}
+
+// WithShards sets the number of shards of internal metrics indexes. The number rounds up to the power of two.
+// By default, the number of shards is equal to GOMAXPROCS. Use 1 to keep single lock per metric type.
+func WithShards(n uint) Option {
+	return func(c *chain) {
+		c.shards = n
+	}
+}
+
+// WithConstLabels sets labels that will be added to every metric of the chain. pairs is a list of label names and
+// values: name0, value0, name1, value1, ...
+//
+// Label added to the chain with the same name as constant label overrides its value.
+func WithConstLabels(pairs ...string) Option {
+	if len(pairs)%2 != 0 {
+		panic("vmchain: odd number of constant labels pairs")
+	}
+	return func(c *chain) {
+		c.cls = append(c.cls, pairs...)
+	}
+}
+
+// WithNamespace sets the namespace of metrics names. Each metric name of the chain will be prefixed with
+// "<namespace>_".
+func WithNamespace(namespace string) Option {
+	return func(c *chain) {
+		c.pfx = namespace + "_"
+	}
+}
+
+// WithCanonicalLabels enables canonical order of labels: labels are sorted by name, so the same set of labels added
+// in different order produces the same series. Duplicate label names are handled according mode.
+func WithCanonicalLabels(mode DuplicateMode) Option {
+	return func(c *chain) {
+		c.srt, c.dup = true, mode
 	}
 }
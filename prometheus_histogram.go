@@ -0,0 +1,157 @@
+package vmchain
+
+import (
+	"context"
+	"time"
+
+	"github.com/koykov/indirect"
+)
+
+type PrometheusHistogramChain interface {
+	WithLabel(name, value string) PrometheusHistogramChain
+	L(name, value string) PrometheusHistogramChain
+	WithAnyLabel(name string, value any) PrometheusHistogramChain
+	AL(name string, value any) PrometheusHistogramChain
+	WithIntLabel(name string, value int64) PrometheusHistogramChain
+	WithUintLabel(name string, value uint64) PrometheusHistogramChain
+	WithFloatLabel(name string, value float64, format byte, prec int) PrometheusHistogramChain
+	WithBoolLabel(name string, value bool) PrometheusHistogramChain
+	WithBytesLabel(name string, value []byte) PrometheusHistogramChain
+	WithDurationLabel(name string, value time.Duration) PrometheusHistogramChain
+	WithContext(ctx context.Context) PrometheusHistogramChain
+	WithStructLabels(v any) PrometheusHistogramChain
+	Update(value float64)
+	UpdateDuration(startTime time.Time)
+	Reset()
+	Start() Timer
+	Unregister() bool
+	Bind() *PrometheusHistogramHandle
+}
+
+type phistogram struct {
+	builder
+	sptr    uintptr
+	buckets []float64
+}
+
+func (h *phistogram) WithLabel(name, value string) PrometheusHistogramChain {
+	h.setLabel(name, value)
+	return h
+}
+
+func (h *phistogram) L(name, value string) PrometheusHistogramChain {
+	return h.WithLabel(name, value)
+}
+
+func (h *phistogram) WithAnyLabel(name string, value any) PrometheusHistogramChain {
+	h.setAnyLabel(name, value)
+	return h
+}
+
+func (h *phistogram) AL(name string, value any) PrometheusHistogramChain {
+	return h.WithAnyLabel(name, value)
+}
+
+func (h *phistogram) WithIntLabel(name string, value int64) PrometheusHistogramChain {
+	h.setIntLabel(name, value)
+	return h
+}
+
+func (h *phistogram) WithUintLabel(name string, value uint64) PrometheusHistogramChain {
+	h.setUintLabel(name, value)
+	return h
+}
+
+func (h *phistogram) WithFloatLabel(name string, value float64, format byte, prec int) PrometheusHistogramChain {
+	h.setFloatLabel(name, value, format, prec)
+	return h
+}
+
+func (h *phistogram) WithBoolLabel(name string, value bool) PrometheusHistogramChain {
+	h.setBoolLabel(name, value)
+	return h
+}
+
+func (h *phistogram) WithBytesLabel(name string, value []byte) PrometheusHistogramChain {
+	h.setBytesLabel(name, value)
+	return h
+}
+
+func (h *phistogram) WithDurationLabel(name string, value time.Duration) PrometheusHistogramChain {
+	h.setDurationLabel(name, value)
+	return h
+}
+
+func (h *phistogram) WithContext(ctx context.Context) PrometheusHistogramChain {
+	h.setContextLabels(ctx)
+	return h
+}
+
+func (h *phistogram) WithStructLabels(v any) PrometheusHistogramChain {
+	h.setStructLabels(v)
+	return h
+}
+
+func (h *phistogram) Update(value float64) {
+	if s := h.indirectSet(); s != nil {
+		defer s.releasePHistogram(h)
+		if m := s.getPHistogram(&h.builder, h.buckets); m != nil {
+			m.Update(value)
+		}
+	}
+}
+
+func (h *phistogram) UpdateDuration(startTime time.Time) {
+	if s := h.indirectSet(); s != nil {
+		defer s.releasePHistogram(h)
+		if m := s.getPHistogram(&h.builder, h.buckets); m != nil {
+			m.UpdateDuration(startTime)
+		}
+	}
+}
+
+func (h *phistogram) Reset() {
+	if s := h.indirectSet(); s != nil {
+		defer s.releasePHistogram(h)
+		if m := s.getPHistogram(&h.builder, h.buckets); m != nil {
+			m.Reset()
+		}
+	}
+}
+
+func (h *phistogram) Start() Timer {
+	return startTimer(h)
+}
+
+func (h *phistogram) Unregister() bool {
+	if s := h.indirectSet(); s != nil {
+		defer s.releasePHistogram(h)
+		return s.unregister(&s.pst, &h.builder)
+	}
+	return false
+}
+
+func (h *phistogram) Bind() *PrometheusHistogramHandle {
+	var hd PrometheusHistogramHandle
+	if s := h.indirectSet(); s != nil {
+		defer s.releasePHistogram(h)
+		buckets := h.buckets
+		hd.init(s, &s.pst, &h.builder, func(fullName string) any {
+			return s.be.PrometheusHistogram(fullName, buckets)
+		})
+	}
+	return &hd
+}
+
+func (h *phistogram) indirectSet() *chain {
+	if h.sptr == 0 || h.raw {
+		return nil
+	}
+	return (*chain)(indirect.ToUnsafePtr(h.sptr))
+}
+
+func (h *phistogram) reset() {
+	h.builder.reset()
+	h.sptr = 0
+	h.buckets = nil
+}
@@ -0,0 +1,84 @@
+package vmchain
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func phfn() PrometheusHistogramChain {
+	return PrometheusHistogram("myservice_feature_phistogram").
+		WithLabel("groupID", "foobar").
+		WithLabel("countryID", "123")
+}
+
+func TestPrometheusHistogram(t *testing.T) {
+	t.Run("update", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.PrometheusHistogram("myservice_phistogram").WithLabel("stage", "auth").Update(0.3)
+		c.PrometheusHistogram("myservice_phistogram").WithLabel("stage", "auth").Update(3)
+		var buf bytes.Buffer
+		set.WritePrometheus(&buf)
+		assert.Contains(t, buf.String(), `myservice_phistogram_bucket{stage="auth",le="0.5"} 1`)
+		assert.Contains(t, buf.String(), `myservice_phistogram_bucket{stage="auth",le="5"} 2`)
+		assert.Contains(t, buf.String(), `myservice_phistogram_count{stage="auth"} 2`)
+	})
+	t.Run("ext", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.PHE("myservice_phistogram", []float64{1, 10, 100}).AL("groupID", 15).Update(42)
+		var buf bytes.Buffer
+		set.WritePrometheus(&buf)
+		assert.Contains(t, buf.String(), `myservice_phistogram_bucket{groupID="15",le="10"} 0`)
+		assert.Contains(t, buf.String(), `myservice_phistogram_bucket{groupID="15",le="100"} 1`)
+		assert.Contains(t, buf.String(), `myservice_phistogram_bucket{groupID="15",le="+Inf"} 1`)
+	})
+	t.Run("reset", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.PH("myservice_phistogram").UpdateDuration(time.Now())
+		c.PH("myservice_phistogram").Reset()
+		var buf bytes.Buffer
+		set.WritePrometheus(&buf)
+		assert.Contains(t, buf.String(), `myservice_phistogram_count 0`)
+	})
+	t.Run("unregister", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.PrometheusHistogram("tenant_latency_seconds").WithLabel("tenant", "a").Update(1)
+		assert.True(t, c.PrometheusHistogram("tenant_latency_seconds").WithLabel("tenant", "a").Unregister())
+		assert.False(t, c.PrometheusHistogram("tenant_latency_seconds").WithLabel("tenant", "a").Unregister())
+		assert.Empty(t, set.ListMetricNames())
+	})
+}
+
+func BenchmarkPrometheusHistogram(b *testing.B) {
+	b.Run("update", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			phfn().Update(1)
+		}
+	})
+	b.Run("update duration", func(b *testing.B) {
+		b.ReportAllocs()
+		tm, _ := time.Parse(time.DateTime, time.DateTime)
+		for i := 0; i < b.N; i++ {
+			phfn().UpdateDuration(tm)
+		}
+	})
+}
+
+func BenchmarkPrometheusHistogramParallel(b *testing.B) {
+	b.Run("update", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				phfn().Update(1)
+			}
+		})
+	})
+}
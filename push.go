@@ -0,0 +1,219 @@
+package vmchain
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPushTimeout = 10 * time.Second
+	defaultPushBackoff = 100 * time.Millisecond
+)
+
+// Pusher periodically pushes series of the chain to VictoriaMetrics import endpoint (/api/v1/import/prometheus) or
+// to any other endpoint that accepts Prometheus text format.
+//
+// Use it in batch jobs and CLIs that exit before any scrape happens. Pusher makes the final push on Close.
+type Pusher struct {
+	c     Chain
+	url   string
+	cl    *http.Client
+	ivl   time.Duration
+	tmo   time.Duration
+	retry uint
+	boff  time.Duration
+	hdr   http.Header
+	onErr func(error)
+
+	mux  sync.Mutex
+	buf  bytes.Buffer
+	zw   *gzip.Writer
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+	cerr error
+}
+
+type PushOption func(p *Pusher)
+
+// WithPushInterval enables periodic push with given interval. By default, series pushes only on Flush and Close.
+func WithPushInterval(interval time.Duration) PushOption {
+	return func(p *Pusher) {
+		p.ivl = interval
+	}
+}
+
+// WithPushExtraLabel adds label to all pushed series. Label passes to VM using extra_label query parameter.
+func WithPushExtraLabel(name, value string) PushOption {
+	return func(p *Pusher) {
+		u, _ := url.Parse(p.url)
+		q := u.Query()
+		q.Add("extra_label", name+"="+value)
+		u.RawQuery = q.Encode()
+		p.url = u.String()
+	}
+}
+
+// WithPushTimeout sets timeout of single push attempt. Default timeout is 10 seconds.
+func WithPushTimeout(timeout time.Duration) PushOption {
+	return func(p *Pusher) {
+		p.tmo = timeout
+	}
+}
+
+// WithPushRetry enables retries of failed pushes. Delay between attempts starts from backoff and doubles after each
+// attempt. Pushes rejected with 4xx status don't retry.
+func WithPushRetry(retries uint, backoff time.Duration) PushOption {
+	return func(p *Pusher) {
+		p.retry, p.boff = retries, backoff
+	}
+}
+
+// WithPushHeader adds HTTP header to push requests (authorization, tenant headers, ...).
+func WithPushHeader(name, value string) PushOption {
+	return func(p *Pusher) {
+		p.hdr.Add(name, value)
+	}
+}
+
+// WithPushClient sets HTTP client to use. By default, http.DefaultClient uses.
+func WithPushClient(cl *http.Client) PushOption {
+	return func(p *Pusher) {
+		p.cl = cl
+	}
+}
+
+// WithPushErrorHandler sets the function to pass errors of periodic pushes.
+func WithPushErrorHandler(fn func(error)) PushOption {
+	return func(p *Pusher) {
+		p.onErr = fn
+	}
+}
+
+// NewPusher makes new pusher of chain series to pushURL, e.g. http://victoria-metrics:8428/api/v1/import/prometheus.
+func NewPusher(c Chain, pushURL string, opts ...PushOption) (*Pusher, error) {
+	u, err := url.Parse(pushURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse push URL %q: %w", pushURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme in push URL %q; expecting http or https", pushURL)
+	}
+	p := &Pusher{
+		c:    c,
+		url:  pushURL,
+		cl:   http.DefaultClient,
+		tmo:  defaultPushTimeout,
+		boff: defaultPushBackoff,
+		hdr:  make(http.Header),
+		done: make(chan struct{}),
+	}
+	for _, fn := range opts {
+		fn(p)
+	}
+	p.zw = gzip.NewWriter(io.Discard)
+	if p.ivl > 0 {
+		p.wg.Add(1)
+		go p.loop()
+	}
+	return p, nil
+}
+
+// Flush pushes series immediately.
+func (p *Pusher) Flush(ctx context.Context) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	p.buf.Reset()
+	p.zw.Reset(&p.buf)
+	p.c.WritePrometheus(p.zw)
+	if err := p.zw.Close(); err != nil {
+		return err
+	}
+
+	boff := p.boff
+	for i := uint(0); ; i++ {
+		retry, err := p.push(ctx, p.buf.Bytes())
+		if err == nil || !retry || i == p.retry {
+			return err
+		}
+		select {
+		case <-time.After(boff):
+			boff *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close stops periodic push and makes the final push.
+func (p *Pusher) Close() error {
+	p.once.Do(func() {
+		close(p.done)
+		p.wg.Wait()
+		ctx, cancel := context.WithTimeout(context.Background(), p.tmo*time.Duration(p.retry+1))
+		defer cancel()
+		p.cerr = p.Flush(ctx)
+	})
+	return p.cerr
+}
+
+// push makes single push attempt and returns whether the attempt may be retried.
+func (p *Pusher) push(ctx context.Context, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.tmo)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	for k, v := range p.hdr {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentTypeText)
+	req.Header.Set("Content-Encoding", "gzip")
+
+	resp, err := p.cl.Do(req)
+	if err != nil {
+		return ctx.Err() == nil || ctx.Err() == context.DeadlineExceeded, fmt.Errorf("cannot push metrics to %q: %w", p.url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests,
+			fmt.Errorf("unexpected status code %d while pushing metrics to %q: %s", resp.StatusCode, p.url, msg)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return false, nil
+}
+
+func (p *Pusher) loop() {
+	defer p.wg.Done()
+	t := time.NewTicker(p.ivl)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				select {
+				case <-p.done:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			if err := p.Flush(ctx); err != nil && p.onErr != nil {
+				p.onErr(err)
+			}
+			cancel()
+		case <-p.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package vmchain
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// importServer is a stand-in of VM import endpoint.
+type importServer struct {
+	*httptest.Server
+	mux    sync.Mutex
+	bodies []string
+	query  string
+	fail   atomic.Int32
+	status int
+}
+
+func newImportServer() *importServer {
+	s := &importServer{status: http.StatusServiceUnavailable}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.fail.Add(-1) >= 0 {
+			w.WriteHeader(s.status)
+			return
+		}
+		zr, _ := gzip.NewReader(r.Body)
+		body, _ := io.ReadAll(zr)
+		s.mux.Lock()
+		s.bodies = append(s.bodies, string(body))
+		s.query = r.URL.RawQuery
+		s.mux.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	return s
+}
+
+func (s *importServer) pushes() []string {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func TestPusher(t *testing.T) {
+	t.Run("flush", func(t *testing.T) {
+		srv := newImportServer()
+		defer srv.Close()
+		c := NewChain(WithVMSet(metrics.NewSet()))
+		c.Counter("jobs_total").WithLabel("status", "ok").Add(3)
+		p, err := NewPusher(c, srv.URL+"/api/v1/import/prometheus",
+			WithPushExtraLabel("job", "backup"), WithPushExtraLabel("instance", "host1"))
+		assert.NoError(t, err)
+		assert.NoError(t, p.Flush(context.Background()))
+		assert.Equal(t, []string{"jobs_total{status=\"ok\"} 3\n"}, srv.pushes())
+		assert.Equal(t, "extra_label=job%3Dbackup&extra_label=instance%3Dhost1", srv.query)
+	})
+	t.Run("close", func(t *testing.T) {
+		srv := newImportServer()
+		defer srv.Close()
+		c := NewChain(WithVMSet(metrics.NewSet()))
+		p, _ := NewPusher(c, srv.URL)
+		c.Counter("jobs_total").Inc()
+		assert.NoError(t, p.Close())
+		assert.NoError(t, p.Close())
+		assert.Equal(t, []string{"jobs_total 1\n"}, srv.pushes())
+	})
+	t.Run("interval", func(t *testing.T) {
+		srv := newImportServer()
+		defer srv.Close()
+		c := NewChain(WithVMSet(metrics.NewSet()))
+		c.Counter("jobs_total").Inc()
+		p, _ := NewPusher(c, srv.URL, WithPushInterval(time.Millisecond))
+		assert.Eventually(t, func() bool { return len(srv.pushes()) >= 2 }, time.Second, time.Millisecond)
+		assert.NoError(t, p.Close())
+	})
+	t.Run("retry", func(t *testing.T) {
+		srv := newImportServer()
+		defer srv.Close()
+		srv.fail.Store(2)
+		c := NewChain(WithVMSet(metrics.NewSet()))
+		c.Counter("jobs_total").Inc()
+		p, _ := NewPusher(c, srv.URL, WithPushRetry(2, time.Millisecond))
+		assert.NoError(t, p.Flush(context.Background()))
+		assert.Equal(t, []string{"jobs_total 1\n"}, srv.pushes())
+
+		srv.fail.Store(3)
+		assert.Error(t, p.Flush(context.Background()))
+	})
+	t.Run("no retry on client error", func(t *testing.T) {
+		srv := newImportServer()
+		defer srv.Close()
+		srv.status = http.StatusBadRequest
+		srv.fail.Store(1)
+		c := NewChain(WithVMSet(metrics.NewSet()))
+		p, _ := NewPusher(c, srv.URL, WithPushRetry(2, time.Millisecond))
+		assert.ErrorContains(t, p.Flush(context.Background()), "unexpected status code 400")
+		assert.Empty(t, srv.pushes())
+	})
+	t.Run("timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer srv.Close()
+		defer close(release)
+		p, _ := NewPusher(NewChain(WithVMSet(metrics.NewSet())), srv.URL, WithPushTimeout(10*time.Millisecond))
+		assert.ErrorIs(t, p.Flush(context.Background()), context.DeadlineExceeded)
+	})
+	t.Run("invalid url", func(t *testing.T) {
+		_, err := NewPusher(NewChain(), "ftp://localhost")
+		assert.Error(t, err)
+	})
+}
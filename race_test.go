@@ -0,0 +1,6 @@
+//go:build race
+
+package vmchain
+
+// Race detector instrumentation allocates, so allocation checks are meaningless.
+const raceEnabled = true
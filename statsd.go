@@ -0,0 +1,487 @@
+package vmchain
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultStatsDFlushInterval = time.Second
+	// Safe payload size of UDP packet for Ethernet MTU.
+	defaultStatsDPacketSize = 1432
+	defaultStatsDMaxSamples = 1024
+)
+
+type StatsDOption func(sd *statsd)
+
+// WithStatsDFlushInterval sets interval of sending aggregated metrics to StatsD agent. Default interval is 1 second.
+// Zero interval disables periodic flush, so metrics sends only on Chain.Close, Unregister or when buffer of samples is
+// full (see WithStatsDMaxSamples).
+func WithStatsDFlushInterval(interval time.Duration) StatsDOption {
+	return func(sd *statsd) {
+		sd.ivl = interval
+	}
+}
+
+// WithStatsDErrorHandler sets the function to pass network errors.
+func WithStatsDErrorHandler(fn func(error)) StatsDOption {
+	return func(sd *statsd) {
+		sd.onErr = fn
+	}
+}
+
+// WithStatsDPacketSize sets max size of UDP packet. Default size is 1432 bytes.
+func WithStatsDPacketSize(size int) StatsDOption {
+	return func(sd *statsd) {
+		sd.psize = size
+	}
+}
+
+// WithStatsDMaxSamples sets max number of samples that histogram or summary keeps between flushes. Full buffer of
+// samples sends to StatsD agent immediately. Default number is 1024, non-positive n disables the limit.
+func WithStatsDMaxSamples(n int) StatsDOption {
+	return func(sd *statsd) {
+		sd.smax = n
+	}
+}
+
+// statsd is a backend that keeps metrics created by chain, aggregates their values and periodically sends them to
+// StatsD agent.
+type statsd struct {
+	addr  string
+	ivl   time.Duration
+	psize int
+	smax  int
+	onErr func(error)
+	conn  net.Conn
+
+	mux  sync.Mutex
+	idx  map[string]sdMetric
+	list []sdMetric
+
+	// Flush state, protected by fmux.
+	fmux sync.Mutex
+	snap []sdMetric
+	line []byte
+	pkt  []byte
+
+	done chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// sdMetric is a metric that writes lines of its aggregated values on flush.
+type sdMetric interface {
+	flush(sd *statsd)
+}
+
+// NewStatsDBackend makes backend that sends metrics to StatsD agent at addr (host:port) over UDP.
+//
+// Labels render as DogStatsD tags. Counters and gauges aggregate on client side, samples of histograms and summaries
+// send as is. Metrics lines batch into packets and send periodically, see StatsDOption. Backend makes the final flush
+// on Close.
+func NewStatsDBackend(addr string, options ...StatsDOption) Backend {
+	sd := &statsd{
+		addr:  addr,
+		ivl:   defaultStatsDFlushInterval,
+		psize: defaultStatsDPacketSize,
+		smax:  defaultStatsDMaxSamples,
+		idx:   make(map[string]sdMetric),
+		done:  make(chan struct{}),
+	}
+	for _, fn := range options {
+		fn(sd)
+	}
+	conn, err := net.Dial("udp", sd.addr)
+	if err != nil {
+		sd.report(fmt.Errorf("cannot connect to StatsD agent %q: %w", sd.addr, err))
+	}
+	sd.conn = conn
+	if sd.ivl > 0 {
+		sd.wg.Add(1)
+		go sd.loop()
+	}
+	return sd
+}
+
+func (sd *statsd) Gauge(fullName string, f func() float64) GaugeMetric {
+	return sdGetOrCreate(sd, fullName, func(s sdSeries) *sdGauge { return &sdGauge{sdSeries: s, f: f} })
+}
+
+func (sd *statsd) Counter(fullName string) CounterMetric {
+	return sdGetOrCreate(sd, fullName, func(s sdSeries) *sdCounter { return &sdCounter{sdSeries: s} })
+}
+
+func (sd *statsd) FloatCounter(fullName string) FloatCounterMetric {
+	return sdGetOrCreate(sd, fullName, func(s sdSeries) *sdFCounter { return &sdFCounter{sdSeries: s} })
+}
+
+func (sd *statsd) Histogram(fullName string) HistogramMetric {
+	return sd.samples(fullName)
+}
+
+// Summary ignores window and quantiles, since StatsD agent calculates percentiles itself.
+func (sd *statsd) Summary(fullName string, _ time.Duration, _ []float64) SummaryMetric {
+	return sd.samples(fullName)
+}
+
+// PrometheusHistogram ignores upperBounds, since StatsD agent builds buckets itself.
+func (sd *statsd) PrometheusHistogram(fullName string, _ []float64) PrometheusHistogramMetric {
+	return sd.samples(fullName)
+}
+
+func (sd *statsd) Close() (err error) {
+	sd.once.Do(func() {
+		close(sd.done)
+		sd.wg.Wait()
+		sd.flush()
+		if sd.conn != nil {
+			err = sd.conn.Close()
+		}
+	})
+	return
+}
+
+func (sd *statsd) loop() {
+	defer sd.wg.Done()
+	t := time.NewTicker(sd.ivl)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			sd.flush()
+		case <-sd.done:
+			return
+		}
+	}
+}
+
+// sdGetOrCreate returns metric registered with fullName or registers new one using fn.
+func sdGetOrCreate[T sdMetric](sd *statsd, fullName string, fn func(s sdSeries) T) T {
+	sd.mux.Lock()
+	defer sd.mux.Unlock()
+	if m, ok := sd.idx[fullName]; ok {
+		if t, ok := m.(T); ok {
+			return t
+		}
+		panic(fmt.Errorf("BUG: metric %q is already registered with another type %T", fullName, m))
+	}
+	m := fn(newSDSeries(fullName))
+	sd.idx[fullName] = m
+	sd.list = append(sd.list, m)
+	return m
+}
+
+func (sd *statsd) samples(fullName string) *sdSamples {
+	return sdGetOrCreate(sd, fullName, func(s sdSeries) *sdSamples { return &sdSamples{sdSeries: s, sd: sd} })
+}
+
+// Unregister sends unflushed values of the metric to the agent and removes it.
+func (sd *statsd) Unregister(fullName string) bool {
+	sd.fmux.Lock()
+	defer sd.fmux.Unlock()
+
+	sd.mux.Lock()
+	m, ok := sd.idx[fullName]
+	if ok {
+		delete(sd.idx, fullName)
+		for i := range sd.list {
+			if sd.list[i] == m {
+				sd.list = append(sd.list[:i], sd.list[i+1:]...)
+				break
+			}
+		}
+	}
+	sd.mux.Unlock()
+
+	if ok {
+		m.flush(sd)
+		sd.send()
+	}
+	return ok
+}
+
+// flush sends aggregated values of all metrics to the agent.
+func (sd *statsd) flush() {
+	sd.fmux.Lock()
+	defer sd.fmux.Unlock()
+
+	sd.mux.Lock()
+	sd.snap = append(sd.snap[:0], sd.list...)
+	sd.mux.Unlock()
+
+	for _, m := range sd.snap {
+		m.flush(sd)
+	}
+	sd.send()
+	clear(sd.snap)
+}
+
+// flushMetric sends aggregated values of single metric to the agent.
+func (sd *statsd) flushMetric(m sdMetric) {
+	sd.fmux.Lock()
+	defer sd.fmux.Unlock()
+	m.flush(sd)
+	sd.send()
+}
+
+func (sd *statsd) writeInt(s *sdSeries, value int64, typ string) {
+	sd.line = append(sd.line[:0], s.name...)
+	sd.line = append(sd.line, ':')
+	sd.line = strconv.AppendInt(sd.line, value, 10)
+	sd.write(s, typ)
+}
+
+func (sd *statsd) writeFloat(s *sdSeries, value float64, typ string) {
+	sd.line = append(sd.line[:0], s.name...)
+	sd.line = append(sd.line, ':')
+	sd.line = strconv.AppendFloat(sd.line, value, 'g', -1, 64)
+	sd.write(s, typ)
+}
+
+// write completes the line and adds it to the packet. Full packet sends to the agent.
+func (sd *statsd) write(s *sdSeries, typ string) {
+	sd.line = append(sd.line, '|')
+	sd.line = append(sd.line, typ...)
+	if len(s.tags) > 0 {
+		sd.line = append(sd.line, "|#"...)
+		sd.line = append(sd.line, s.tags...)
+	}
+	if len(sd.pkt) > 0 && len(sd.pkt)+1+len(sd.line) > sd.psize {
+		sd.send()
+	}
+	if len(sd.pkt) > 0 {
+		sd.pkt = append(sd.pkt, '\n')
+	}
+	sd.pkt = append(sd.pkt, sd.line...)
+}
+
+func (sd *statsd) send() {
+	if len(sd.pkt) == 0 {
+		return
+	}
+	if sd.conn != nil {
+		if _, err := sd.conn.Write(sd.pkt); err != nil {
+			sd.report(fmt.Errorf("cannot send metrics to StatsD agent %q: %w", sd.addr, err))
+		}
+	}
+	sd.pkt = sd.pkt[:0]
+}
+
+func (sd *statsd) report(err error) {
+	if sd.onErr != nil {
+		sd.onErr(err)
+	}
+}
+
+// sdSeries keeps StatsD representation of full metric name.
+type sdSeries struct {
+	name string
+	tags string
+}
+
+// newSDSeries converts full name like `name{k0="v0",k1="v1"}` to name and DogStatsD tags `k0:v0,k1:v1`.
+// Characters that have special meaning in StatsD protocol replace with underscore.
+func newSDSeries(fullName string) sdSeries {
+	var s sdSeries
+	i := 0
+	for i < len(fullName) && fullName[i] != '{' {
+		i++
+	}
+	s.name = string(sdSanitize(nil, fullName[:i], false))
+
+	var tags []byte
+	for i++; i < len(fullName); i++ {
+		// Label name.
+		lo := i
+		for i < len(fullName) && fullName[i] != '=' {
+			i++
+		}
+		if i+1 >= len(fullName) {
+			break
+		}
+		if len(tags) > 0 {
+			tags = append(tags, ',')
+		}
+		tags = sdSanitize(tags, fullName[lo:i], false)
+		tags = append(tags, ':')
+		// Quoted and escaped label value.
+		lo, i = i+2, i+2
+		for i < len(fullName) && fullName[i] != '"' {
+			if fullName[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i > len(fullName) {
+			i = len(fullName)
+		}
+		tags = sdSanitize(tags, fullName[lo:i], true)
+		// Skip closing quote and comma.
+		i++
+	}
+	s.tags = string(tags)
+	return s
+}
+
+func sdSanitize(dst []byte, s string, unescape bool) []byte {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if unescape && c == '\\' && i+1 < len(s) {
+			i++
+			if c = s[i]; c == 'n' {
+				c = '\n'
+			}
+		}
+		switch c {
+		case ':', '|', ',', '#', '@', '\n':
+			c = '_'
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
+
+// sdCounter sends increments of the counter since the last flush.
+type sdCounter struct {
+	sdSeries
+	v    atomic.Int64
+	sent int64
+}
+
+func (c *sdCounter) Add(value int)        { c.v.Add(int64(value)) }
+func (c *sdCounter) AddInt64(value int64) { c.v.Add(value) }
+func (c *sdCounter) Set(value uint64)     { c.v.Store(int64(value)) }
+func (c *sdCounter) Inc()                 { c.v.Add(1) }
+func (c *sdCounter) Dec()                 { c.v.Add(-1) }
+func (c *sdCounter) Get() uint64          { return uint64(c.v.Load()) }
+
+func (c *sdCounter) flush(sd *statsd) {
+	v := c.v.Load()
+	if d := v - c.sent; d != 0 {
+		sd.writeInt(&c.sdSeries, d, "c")
+		c.sent = v
+	}
+}
+
+// sdFCounter sends increments of the float counter since the last flush.
+type sdFCounter struct {
+	sdSeries
+	v    atomicFloat
+	sent float64
+}
+
+func (c *sdFCounter) Add(value float64) { c.v.add(value) }
+func (c *sdFCounter) Sub(value float64) { c.v.add(-value) }
+func (c *sdFCounter) Set(value float64) { c.v.store(value) }
+func (c *sdFCounter) Get() float64      { return c.v.load() }
+
+func (c *sdFCounter) flush(sd *statsd) {
+	v := c.v.load()
+	if d := v - c.sent; d != 0 {
+		sd.writeFloat(&c.sdSeries, d, "c")
+		c.sent = v
+	}
+}
+
+// sdGauge sends the last value of the gauge if it was changed since the last flush. Gauge with callback sends on
+// every flush.
+type sdGauge struct {
+	sdSeries
+	v     atomicFloat
+	f     func() float64
+	dirty atomic.Bool
+}
+
+func (g *sdGauge) Add(value float64) { g.v.add(value); g.dirty.Store(true) }
+func (g *sdGauge) Set(value float64) { g.v.store(value); g.dirty.Store(true) }
+func (g *sdGauge) Inc()              { g.Add(1) }
+func (g *sdGauge) Dec()              { g.Add(-1) }
+
+func (g *sdGauge) Get() float64 {
+	if g.f != nil {
+		return g.f()
+	}
+	return g.v.load()
+}
+
+func (g *sdGauge) flush(sd *statsd) {
+	if g.f == nil && !g.dirty.Swap(false) {
+		return
+	}
+	v := g.Get()
+	if v < 0 {
+		// Signed value means relative change of the gauge in StatsD, so reset it first.
+		sd.writeInt(&g.sdSeries, 0, "g")
+	}
+	sd.writeFloat(&g.sdSeries, v, "g")
+}
+
+// sdSamples sends all observed values of histograms and summaries since the last flush. Full buffer of samples (see
+// WithStatsDMaxSamples) sends immediately, so memory stays bounded regardless flush interval.
+type sdSamples struct {
+	sdSeries
+	sd   *statsd
+	mux  sync.Mutex
+	buf  []float64
+	swap []float64
+}
+
+func (h *sdSamples) Update(value float64) {
+	h.mux.Lock()
+	h.buf = append(h.buf, value)
+	full := h.sd.smax > 0 && len(h.buf) >= h.sd.smax
+	h.mux.Unlock()
+	if full {
+		h.sd.flushMetric(h)
+	}
+}
+
+func (h *sdSamples) UpdateDuration(startTime time.Time) {
+	h.Update(time.Since(startTime).Seconds())
+}
+
+// VisitNonZeroBuckets does nothing, since StatsD agent builds buckets itself.
+func (h *sdSamples) VisitNonZeroBuckets(func(vmrange string, count uint64)) {}
+
+func (h *sdSamples) Reset() {
+	h.mux.Lock()
+	h.buf = h.buf[:0]
+	h.mux.Unlock()
+}
+
+func (h *sdSamples) flush(sd *statsd) {
+	h.mux.Lock()
+	h.buf, h.swap = h.swap[:0], h.buf
+	h.mux.Unlock()
+	for _, v := range h.swap {
+		sd.writeFloat(&h.sdSeries, v, "h")
+	}
+}
+
+// atomicFloat is a float64 with atomic operations.
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+func (f *atomicFloat) store(v float64) {
+	f.bits.Store(math.Float64bits(v))
+}
+
+func (f *atomicFloat) add(v float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
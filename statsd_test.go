@@ -0,0 +1,142 @@
+package vmchain
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// listenUDP starts local UDP listener and returns its address and function to read received packets.
+func listenUDP(t *testing.T) (string, func() []string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn.LocalAddr().String(), func() (pkts []string) {
+		buf := make([]byte, 64*1024)
+		for {
+			_ = conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			pkts = append(pkts, string(buf[:n]))
+		}
+	}
+}
+
+func TestStatsD(t *testing.T) {
+	t.Run("aggregation", func(t *testing.T) {
+		addr, read := listenUDP(t)
+		c := NewChain(WithStatsD(addr, WithStatsDFlushInterval(0)), WithConstLabels("service", "api"))
+		for i := 0; i < 3; i++ {
+			c.Counter("requests_total").WithLabel("method", "GET").Inc()
+		}
+		c.FloatCounter("bytes_total").Add(1.5)
+		c.Gauge("sessions", nil).Set(2)
+		c.Gauge("sessions", nil).Add(1)
+		c.Gauge("temperature", nil).Set(-5)
+		c.Gauge("goroutines", func() float64 { return 10 }).Get()
+		c.Histogram("latency_seconds").WithLabel("route", "/").Update(0.5)
+		c.Summary("size_bytes").Update(100)
+		c.PrometheusHistogram("size_kb").Update(1)
+		assert.Equal(t, uint64(3), c.Counter("requests_total").WithLabel("method", "GET").Get())
+		assert.NoError(t, c.Close())
+		assert.Equal(t, []string{strings.Join([]string{
+			"requests_total:3|c|#service:api,method:GET",
+			"bytes_total:1.5|c|#service:api",
+			"sessions:3|g|#service:api",
+			"temperature:0|g|#service:api",
+			"temperature:-5|g|#service:api",
+			"goroutines:10|g|#service:api",
+			"latency_seconds:0.5|h|#service:api,route:/",
+			"size_bytes:100|h|#service:api",
+			"size_kb:1|h|#service:api",
+		}, "\n")}, read())
+	})
+	t.Run("delta", func(t *testing.T) {
+		addr, read := listenUDP(t)
+		c := NewChain(WithStatsD(addr, WithStatsDFlushInterval(0)))
+		sd := c.(*chain).be.(*statsd)
+		c.Counter("requests_total").Add(5)
+		c.Gauge("sessions", nil).Set(1)
+		sd.flush()
+		c.Counter("requests_total").Add(2)
+		sd.flush()
+		// Nothing changed.
+		sd.flush()
+		assert.Equal(t, []string{"requests_total:5|c\nsessions:1|g", "requests_total:2|c"}, read())
+	})
+	t.Run("batching", func(t *testing.T) {
+		addr, read := listenUDP(t)
+		c := NewChain(WithStatsD(addr, WithStatsDFlushInterval(0), WithStatsDPacketSize(40)))
+		for i := 0; i < 3; i++ {
+			c.Histogram("latency_seconds").Update(float64(i))
+		}
+		assert.NoError(t, c.Close())
+		assert.Equal(t, []string{"latency_seconds:0|h\nlatency_seconds:1|h", "latency_seconds:2|h"}, read())
+	})
+	t.Run("periodic", func(t *testing.T) {
+		addr, read := listenUDP(t)
+		c := NewChain(WithStatsD(addr, WithStatsDFlushInterval(time.Millisecond)))
+		defer func() { _ = c.Close() }()
+		c.Counter("requests_total").Inc()
+		assert.Equal(t, []string{"requests_total:1|c"}, read())
+	})
+	t.Run("unregister", func(t *testing.T) {
+		addr, read := listenUDP(t)
+		c := NewChain(WithStatsD(addr, WithStatsDFlushInterval(0)))
+		c.Counter("requests_total").WithLabel("method", "GET").Inc()
+		c.Counter("requests_total").WithLabel("method", "POST").Inc()
+		c.Histogram("latency_seconds").Update(1)
+		assert.True(t, c.Counter("requests_total").WithLabel("method", "GET").Unregister())
+		assert.True(t, c.Histogram("latency_seconds").Unregister())
+		assert.NoError(t, c.Close())
+		// Unflushed values send on unregister.
+		assert.Equal(t, []string{
+			"requests_total:1|c|#method:GET",
+			"latency_seconds:1|h",
+			"requests_total:1|c|#method:POST",
+		}, read())
+	})
+	t.Run("max samples", func(t *testing.T) {
+		addr, read := listenUDP(t)
+		c := NewChain(WithStatsD(addr, WithStatsDFlushInterval(0), WithStatsDMaxSamples(2)))
+		for i := 0; i < 5; i++ {
+			c.Summary("size_bytes").Update(float64(i))
+		}
+		sd := c.(*chain).be.(*statsd)
+		assert.LessOrEqual(t, len(sd.idx["size_bytes"].(*sdSamples).buf), 1)
+		assert.NoError(t, c.Close())
+		assert.Equal(t, []string{
+			"size_bytes:0|h\nsize_bytes:1|h",
+			"size_bytes:2|h\nsize_bytes:3|h",
+			"size_bytes:4|h",
+		}, read())
+	})
+	t.Run("backend", func(t *testing.T) {
+		addr, read := listenUDP(t)
+		c := NewChain(WithBackend(NewStatsDBackend(addr, WithStatsDFlushInterval(0))), WithNamespace("api"))
+		c.Counter("requests_total").Inc()
+		assert.NoError(t, c.Close())
+		assert.Equal(t, []string{"api_requests_total:1|c"}, read())
+	})
+	t.Run("tags", func(t *testing.T) {
+		s := newSDSeries(`errors_total{msg="a, \"b\"|c\\d\ne",path="/x:y"}`)
+		assert.Equal(t, "errors_total", s.name)
+		assert.Equal(t, `msg:a_ "b"_c\d_e,path:/x_y`, s.tags)
+	})
+}
+
+func BenchmarkStatsD(b *testing.B) {
+	c := NewChain(WithStatsD("127.0.0.1:8125", WithStatsDFlushInterval(0)))
+	defer func() { _ = c.Close() }()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Counter("requests_total").WithLabel("method", "GET").Inc()
+	}
+}
@@ -0,0 +1,79 @@
+package vmchain
+
+import (
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+)
+
+// storage is a thread-safe index of registered metrics by full name.
+//
+// The index is split to shards by hash of full name to reduce lock contention on multicore machines.
+type storage struct {
+	shards []shard
+	mask   uint64
+}
+
+// shard is a part of storage protected by its own lock.
+type shard struct {
+	mux sync.RWMutex
+	idx map[string]*entry
+	// Padding prevents false sharing of neighbour shards locks.
+	_ [32]byte
+}
+
+// entry represents registered metric.
+type entry struct {
+	m     any
+	fam   *family
+	touch atomic.Int64
+	// Flag indicates that entry was removed from storage.
+	dead atomic.Bool
+	// Flag indicates overflow series (see LimitModeOverflow).
+	ovf bool
+}
+
+var seed = maphash.MakeSeed()
+
+// init makes n shards. n rounds up to the power of two.
+func (s *storage) init(n uint) {
+	c := uint(1)
+	for c < n {
+		c <<= 1
+	}
+	s.shards = make([]shard, c)
+	s.mask = uint64(c - 1)
+}
+
+func (s *storage) shard(fullName string) *shard {
+	if s.mask == 0 {
+		return &s.shards[0]
+	}
+	return &s.shards[maphash.String(seed, fullName)&s.mask]
+}
+
+func (s *storage) get(fullName string) (*entry, bool) {
+	sh := s.shard(fullName)
+	sh.mux.RLock()
+	e, ok := sh.idx[fullName]
+	sh.mux.RUnlock()
+	return e, ok
+}
+
+func (s *storage) len() (n int) {
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mux.RLock()
+		n += len(sh.idx)
+		sh.mux.RUnlock()
+	}
+	return
+}
+
+// set stores the metric. Caller must hold write lock.
+func (sh *shard) set(fullName string, e *entry) {
+	if sh.idx == nil {
+		sh.idx = make(map[string]*entry)
+	}
+	sh.idx[fullName] = e
+}
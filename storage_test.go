@@ -0,0 +1,51 @@
+package vmchain
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage(t *testing.T) {
+	t.Run("shards", func(t *testing.T) {
+		var st storage
+		st.init(5)
+		assert.Len(t, st.shards, 8)
+		st.init(0)
+		assert.Len(t, st.shards, 1)
+	})
+	t.Run("sharded chain", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithShards(16))
+		for i := 0; i < 100; i++ {
+			c.Counter("requests_total").WithAnyLabel("id", i).Inc()
+		}
+		assert.Equal(t, 100, c.(*chain).cst.len())
+		assert.Len(t, set.ListMetricNames(), 100)
+		assert.Equal(t, 100, c.UnregisterFamily("requests_total"))
+		assert.Equal(t, 0, c.(*chain).cst.len())
+	})
+}
+
+// BenchmarkStorageParallel compares single lock index (shards=1) with sharded one on hot path.
+func BenchmarkStorageParallel(b *testing.B) {
+	for _, shards := range []uint{1, 64} {
+		b.Run("shards="+strconv.Itoa(int(shards)), func(b *testing.B) {
+			b.ReportAllocs()
+			c := NewChain(WithVMSet(metrics.NewSet()), WithShards(shards))
+			for i := 0; i < 64; i++ {
+				c.Counter("requests_total").WithAnyLabel("id", i).Inc()
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				var i int
+				for pb.Next() {
+					c.Counter("requests_total").WithAnyLabel("id", i&63).Inc()
+					i++
+				}
+			})
+		})
+	}
+}
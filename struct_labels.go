@@ -0,0 +1,175 @@
+package vmchain
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// StructTag is a name of struct tag that defines label name of the field for WithStructLabels method of chains.
+//
+// WithStructLabels accepts struct or pointer to struct and adds labels from fields with the tag in order of fields.
+// Empty tag value means field name as label name, "-" skips the field. Fields of embedded structs are promoted.
+// Values format the same way as WithAnyLabel does, durations (and pointers to them) format as WithDurationLabel does.
+const StructTag = "vmchain"
+
+// splan is a plan to read labels from struct fields.
+type splan struct {
+	fields []sfield
+	// Pool of struct copies to read struct passed by value.
+	copies sync.Pool
+}
+
+type sfield struct {
+	label string
+	off   uintptr
+	// ptr converts pointer to the field to value suitable for x2bytes.
+	ptr func(p unsafe.Pointer) any
+	dur bool
+	// Field is a pointer to duration.
+	pdur bool
+}
+
+// Plans of struct types.
+var splans sync.Map
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setStructLabels adds labels from fields of struct v marked with "vmchain" tag.
+func (b *builder) setStructLabels(v any) {
+	if v == nil || b.raw {
+		return
+	}
+	t := reflect.TypeOf(v)
+	isPtr := t.Kind() == reflect.Pointer
+	if isPtr {
+		t = t.Elem()
+	}
+	plan := getSPlan(t)
+
+	var p unsafe.Pointer
+	if isPtr {
+		p = reflect.ValueOf(v).UnsafePointer()
+	} else {
+		// Struct value isn't addressable, so copy it to pooled value of the type.
+		cpy := plan.copies.Get()
+		rv := reflect.ValueOf(cpy).Elem()
+		rv.Set(reflect.ValueOf(v))
+		defer func() {
+			rv.SetZero()
+			plan.copies.Put(cpy)
+		}()
+		p = rv.Addr().UnsafePointer()
+	}
+	for i := range plan.fields {
+		f := &plan.fields[i]
+		if p == nil {
+			b.setLabel(f.label, "")
+			continue
+		}
+		fp := unsafe.Add(p, f.off)
+		switch {
+		case f.dur:
+			b.setDurationLabel(f.label, *(*time.Duration)(fp))
+		case f.pdur:
+			if d := *(**time.Duration)(fp); d != nil {
+				b.setDurationLabel(f.label, *d)
+			} else {
+				b.setAnyLabel(f.label, nil)
+			}
+		default:
+			b.setAnyLabel(f.label, f.ptr(fp))
+		}
+	}
+}
+
+func getSPlan(t reflect.Type) *splan {
+	if plan, ok := splans.Load(t); ok {
+		return plan.(*splan)
+	}
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("vmchain: struct or pointer to struct expected, got %s", t))
+	}
+	plan := &splan{}
+	plan.fields = appendSFields(plan.fields, t, 0)
+	plan.copies.New = func() any { return reflect.New(t).Interface() }
+	actual, _ := splans.LoadOrStore(t, plan)
+	return actual.(*splan)
+}
+
+func appendSFields(dst []sfield, t reflect.Type, off uintptr) []sfield {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			// Fields of embedded structs are promoted.
+			dst = appendSFields(dst, sf.Type, off+sf.Offset)
+			continue
+		}
+		label, ok := sf.Tag.Lookup(StructTag)
+		if !ok || label == "-" || !sf.IsExported() {
+			continue
+		}
+		if label == "" {
+			label = sf.Name
+		}
+		dst = append(dst, sfield{
+			label: label,
+			off:   off + sf.Offset,
+			ptr:   fieldPtr(sf.Type),
+			dur:   sf.Type == durationType,
+			pdur:  sf.Type.Kind() == reflect.Pointer && sf.Type.Elem() == durationType,
+		})
+	}
+	return dst
+}
+
+// fieldPtr returns function to make typed pointer to the field. Named types of builtin kinds convert to pointers of
+// builtin types, so x2bytes recognizes them.
+func fieldPtr(t reflect.Type) func(p unsafe.Pointer) any {
+	switch t.Kind() {
+	case reflect.String:
+		return func(p unsafe.Pointer) any { return (*string)(p) }
+	case reflect.Bool:
+		return func(p unsafe.Pointer) any { return (*bool)(p) }
+	case reflect.Int:
+		return func(p unsafe.Pointer) any { return (*int)(p) }
+	case reflect.Int8:
+		return func(p unsafe.Pointer) any { return (*int8)(p) }
+	case reflect.Int16:
+		return func(p unsafe.Pointer) any { return (*int16)(p) }
+	case reflect.Int32:
+		return func(p unsafe.Pointer) any { return (*int32)(p) }
+	case reflect.Int64:
+		return func(p unsafe.Pointer) any { return (*int64)(p) }
+	case reflect.Uint:
+		return func(p unsafe.Pointer) any { return (*uint)(p) }
+	case reflect.Uint8:
+		return func(p unsafe.Pointer) any { return (*uint8)(p) }
+	case reflect.Uint16:
+		return func(p unsafe.Pointer) any { return (*uint16)(p) }
+	case reflect.Uint32:
+		return func(p unsafe.Pointer) any { return (*uint32)(p) }
+	case reflect.Uint64:
+		return func(p unsafe.Pointer) any { return (*uint64)(p) }
+	case reflect.Float32:
+		return func(p unsafe.Pointer) any { return (*float32)(p) }
+	case reflect.Float64:
+		return func(p unsafe.Pointer) any { return (*float64)(p) }
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return func(p unsafe.Pointer) any { return (*[]byte)(p) }
+		}
+	case reflect.Pointer:
+		elem := fieldPtr(t.Elem())
+		return func(p unsafe.Pointer) any {
+			if p = *(*unsafe.Pointer)(p); p == nil {
+				return nil
+			}
+			return elem(p)
+		}
+	}
+	// Custom types pass to x2bytes as is (pointer to value).
+	return func(p unsafe.Pointer) any { return reflect.NewAt(t, p).Interface() }
+}
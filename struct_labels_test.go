@@ -0,0 +1,103 @@
+package vmchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+type testStatus string
+
+type testBase struct {
+	Tenant string `vmchain:"tenant"`
+}
+
+type testRequest struct {
+	testBase
+	Method  string        `vmchain:"method"`
+	Status  testStatus    `vmchain:"status"`
+	Code    int           `vmchain:"code"`
+	Ratio   float64       `vmchain:"ratio"`
+	Cached  bool          `vmchain:"cached"`
+	Path    []byte        `vmchain:"path"`
+	Timeout time.Duration `vmchain:"timeout"`
+	Region  string        `vmchain:""`
+	Body    string        `vmchain:"-"`
+	User    string
+	secret  string `vmchain:"secret"` //nolint:unused
+}
+
+type testPtrOnly struct {
+	Name    *string        `vmchain:"name"`
+	Timeout *time.Duration `vmchain:"timeout"`
+}
+
+func TestStructLabels(t *testing.T) {
+	req := testRequest{
+		testBase: testBase{Tenant: "acme"},
+		Method:   "GET",
+		Status:   "ok",
+		Code:     200,
+		Ratio:    0.5,
+		Cached:   true,
+		Path:     []byte("/users"),
+		Timeout:  time.Second,
+		Region:   "eu",
+		Body:     "body",
+		User:     "john",
+	}
+	const want = `requests_total{tenant="acme",method="GET",status="ok",code="200",ratio="0.5",cached="true",path="/users",timeout="1s",Region="eu"}`
+	t.Run("pointer", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.Counter("requests_total").WithStructLabels(&req).Inc()
+		assert.Equal(t, []string{want}, set.ListMetricNames())
+	})
+	t.Run("value", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.Counter("requests_total").WithStructLabels(req).Inc()
+		name, timeout := "x", time.Second
+		c.Gauge("info", nil).WithStructLabels(testPtrOnly{Name: &name, Timeout: &timeout}).Set(1)
+		c.Gauge("info", nil).WithStructLabels(testPtrOnly{}).Set(1)
+		assert.Equal(t, []string{`info{name="<nil>",timeout="<nil>"}`, `info{name="x",timeout="1s"}`, want}, set.ListMetricNames())
+	})
+	t.Run("nil", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.Counter("requests_total").WithStructLabels((*testBase)(nil)).Inc()
+		c.Counter("requests_total").WithStructLabels(nil).Inc()
+		assert.Equal(t, []string{`requests_total`, `requests_total{tenant=""}`}, set.ListMetricNames())
+	})
+	t.Run("invalid", func(t *testing.T) {
+		c := NewChain(WithVMSet(metrics.NewSet()))
+		assert.Panics(t, func() { c.Counter("requests_total").WithStructLabels(1) })
+	})
+	t.Run("allocs", func(t *testing.T) {
+		if raceEnabled {
+			t.Skip("allocations are inaccurate under race detector")
+		}
+		c := NewChain(WithVMSet(metrics.NewSet()))
+		allocs := testing.AllocsPerRun(100, func() {
+			c.Counter("requests_total").WithStructLabels(&req).Inc()
+		})
+		assert.Equal(t, 0.0, allocs)
+		// Boxing of struct value to interface is a cost of the caller.
+		var v any = req
+		allocs = testing.AllocsPerRun(100, func() {
+			c.Counter("requests_total").WithStructLabels(v).Inc()
+		})
+		assert.Equal(t, 0.0, allocs)
+	})
+}
+
+func BenchmarkStructLabels(b *testing.B) {
+	c := NewChain(WithVMSet(metrics.NewSet()))
+	req := testRequest{testBase: testBase{Tenant: "acme"}, Method: "GET", Status: "ok", Code: 200}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Counter("requests_total").WithStructLabels(&req).Inc()
+	}
+}
@@ -0,0 +1,153 @@
+package vmchain
+
+import (
+	"context"
+	"time"
+
+	"github.com/koykov/indirect"
+)
+
+const defaultSummaryWindow = 5 * time.Minute
+
+var defaultSummaryQuantiles = []float64{0.5, 0.9, 0.97, 0.99, 1}
+
+type SummaryChain interface {
+	WithLabel(name, value string) SummaryChain
+	L(name, value string) SummaryChain
+	WithAnyLabel(name string, value any) SummaryChain
+	AL(name string, value any) SummaryChain
+	WithIntLabel(name string, value int64) SummaryChain
+	WithUintLabel(name string, value uint64) SummaryChain
+	WithFloatLabel(name string, value float64, format byte, prec int) SummaryChain
+	WithBoolLabel(name string, value bool) SummaryChain
+	WithBytesLabel(name string, value []byte) SummaryChain
+	WithDurationLabel(name string, value time.Duration) SummaryChain
+	WithContext(ctx context.Context) SummaryChain
+	WithStructLabels(v any) SummaryChain
+	Update(value float64)
+	UpdateDuration(startTime time.Time)
+	Start() Timer
+	Unregister() bool
+	Bind() *SummaryHandle
+}
+
+type summary struct {
+	builder
+	sptr      uintptr
+	window    time.Duration
+	quantiles []float64
+}
+
+func (s *summary) WithLabel(name, value string) SummaryChain {
+	s.setLabel(name, value)
+	return s
+}
+
+func (s *summary) L(name, value string) SummaryChain {
+	return s.WithLabel(name, value)
+}
+
+func (s *summary) WithAnyLabel(name string, value any) SummaryChain {
+	s.setAnyLabel(name, value)
+	return s
+}
+
+func (s *summary) AL(name string, value any) SummaryChain {
+	return s.WithAnyLabel(name, value)
+}
+
+func (s *summary) WithIntLabel(name string, value int64) SummaryChain {
+	s.setIntLabel(name, value)
+	return s
+}
+
+func (s *summary) WithUintLabel(name string, value uint64) SummaryChain {
+	s.setUintLabel(name, value)
+	return s
+}
+
+func (s *summary) WithFloatLabel(name string, value float64, format byte, prec int) SummaryChain {
+	s.setFloatLabel(name, value, format, prec)
+	return s
+}
+
+func (s *summary) WithBoolLabel(name string, value bool) SummaryChain {
+	s.setBoolLabel(name, value)
+	return s
+}
+
+func (s *summary) WithBytesLabel(name string, value []byte) SummaryChain {
+	s.setBytesLabel(name, value)
+	return s
+}
+
+func (s *summary) WithDurationLabel(name string, value time.Duration) SummaryChain {
+	s.setDurationLabel(name, value)
+	return s
+}
+
+func (s *summary) WithContext(ctx context.Context) SummaryChain {
+	s.setContextLabels(ctx)
+	return s
+}
+
+func (s *summary) WithStructLabels(v any) SummaryChain {
+	s.setStructLabels(v)
+	return s
+}
+
+func (s *summary) Update(value float64) {
+	if c := s.indirectSet(); c != nil {
+		defer c.releaseSummary(s)
+		if m := c.getSummary(&s.builder, s.window, s.quantiles); m != nil {
+			m.Update(value)
+		}
+	}
+}
+
+func (s *summary) UpdateDuration(startTime time.Time) {
+	if c := s.indirectSet(); c != nil {
+		defer c.releaseSummary(s)
+		if m := c.getSummary(&s.builder, s.window, s.quantiles); m != nil {
+			m.UpdateDuration(startTime)
+		}
+	}
+}
+
+func (s *summary) Start() Timer {
+	return startTimer(s)
+}
+
+func (s *summary) Unregister() bool {
+	if c := s.indirectSet(); c != nil {
+		defer c.releaseSummary(s)
+		return c.unregister(&c.sst, &s.builder)
+	}
+	return false
+}
+
+func (s *summary) Bind() *SummaryHandle {
+	var hd SummaryHandle
+	if c := s.indirectSet(); c != nil {
+		defer c.releaseSummary(s)
+		window, quantiles := s.window, s.quantiles
+		hd.init(c, &c.sst, &s.builder, func(fullName string) any {
+			return c.be.Summary(fullName, window, quantiles)
+		})
+	}
+	return &hd
+}
+
+func (s *summary) indirectSet() *chain {
+	if s.sptr == 0 || s.raw {
+		return nil
+	}
+	return (*chain)(indirect.ToUnsafePtr(s.sptr))
+}
+
+func (s *summary) reset() {
+	s.builder.reset()
+	s.sptr = 0
+	s.window = 0
+	s.quantiles = nil
+}
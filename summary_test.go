@@ -0,0 +1,83 @@
+package vmchain
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func sfn() SummaryChain {
+	return Summary("myservice_feature_summary").
+		WithLabel("groupID", "foobar").
+		WithLabel("countryID", "123")
+}
+
+func TestSummary(t *testing.T) {
+	t.Run("update", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.Summary("myservice_summary").WithLabel("stage", "auth").Update(10)
+		c.Summary("myservice_summary").WithLabel("stage", "auth").Update(20)
+		var buf bytes.Buffer
+		set.WritePrometheus(&buf)
+		assert.Contains(t, buf.String(), `myservice_summary_sum{stage="auth"} 30`)
+		assert.Contains(t, buf.String(), `myservice_summary_count{stage="auth"} 2`)
+	})
+	t.Run("update duration", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.S("myservice_summary").L("stage", "auth").UpdateDuration(time.Now())
+		var buf bytes.Buffer
+		set.WritePrometheus(&buf)
+		assert.Contains(t, buf.String(), `myservice_summary_count{stage="auth"} 1`)
+	})
+	t.Run("ext", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.SummaryExt("myservice_summary", time.Minute, []float64{0.5, 0.99}).
+			WithAnyLabel("groupID", 15).
+			Update(5)
+		var buf bytes.Buffer
+		set.WritePrometheus(&buf)
+		assert.Contains(t, buf.String(), `myservice_summary{groupID="15",quantile="0.99"}`)
+		assert.NotContains(t, buf.String(), `quantile="0.9"`)
+	})
+	t.Run("unregister", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		c.Summary("tenant_latency_seconds").WithLabel("tenant", "a").Update(1)
+		assert.True(t, c.Summary("tenant_latency_seconds").WithLabel("tenant", "a").Unregister())
+		assert.False(t, c.Summary("tenant_latency_seconds").WithLabel("tenant", "a").Unregister())
+		assert.Empty(t, set.ListMetricNames())
+	})
+}
+
+func BenchmarkSummary(b *testing.B) {
+	b.Run("update", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sfn().Update(1)
+		}
+	})
+	b.Run("update duration", func(b *testing.B) {
+		b.ReportAllocs()
+		tm, _ := time.Parse(time.DateTime, time.DateTime)
+		for i := 0; i < b.N; i++ {
+			sfn().UpdateDuration(tm)
+		}
+	})
+}
+
+func BenchmarkSummaryParallel(b *testing.B) {
+	b.Run("update", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				sfn().Update(1)
+			}
+		})
+	})
+}
@@ -0,0 +1,65 @@
+package vmchain
+
+import "time"
+
+// Template is a prototype of metric chains with fixed initName and labels.
+//
+// Template builds the name prefix once and each chain made by template starts with a copy of it. Labels of template
+// may be overridden in the chain by labels with the same name. Template is safe for concurrent use.
+type Template struct {
+	c *chain
+	b builder
+}
+
+func (c *chain) Template(initName string, labels ...string) *Template {
+	if len(labels)%2 != 0 {
+		panic("vmchain: odd number of template labels pairs")
+	}
+	t := &Template{c: c}
+	c.prepareBuilder(&t.b, nil, initName)
+	for i := 0; i < len(labels); i += 2 {
+		t.b.setLabel(labels[i], labels[i+1])
+	}
+	t.b.fix()
+	return t
+}
+
+// Gauge makes gauge chain from the template.
+func (t *Template) Gauge(f func() float64) GaugeChain {
+	return t.c.acquireGauge(&t.b, "", f)
+}
+
+// Counter makes counter chain from the template.
+func (t *Template) Counter() CounterChain {
+	return t.c.acquireCounter(&t.b, "")
+}
+
+// FloatCounter makes float counter chain from the template.
+func (t *Template) FloatCounter() FloatCounterChain {
+	return t.c.acquireFCounter(&t.b, "")
+}
+
+// Histogram makes histogram chain from the template.
+func (t *Template) Histogram() HistogramChain {
+	return t.c.acquireHistogram(&t.b, "")
+}
+
+// Summary makes summary chain from the template.
+func (t *Template) Summary() SummaryChain {
+	return t.c.acquireSummary(&t.b, "", 0, nil)
+}
+
+// SummaryExt makes summary chain with custom window and quantiles from the template.
+func (t *Template) SummaryExt(window time.Duration, quantiles []float64) SummaryChain {
+	return t.c.acquireSummary(&t.b, "", window, quantiles)
+}
+
+// PrometheusHistogram makes Prometheus-style histogram chain from the template.
+func (t *Template) PrometheusHistogram() PrometheusHistogramChain {
+	return t.c.acquirePHistogram(&t.b, "", nil)
+}
+
+// PrometheusHistogramExt makes Prometheus-style histogram chain with given buckets upper bounds from the template.
+func (t *Template) PrometheusHistogramExt(upperBounds []float64) PrometheusHistogramChain {
+	return t.c.acquirePHistogram(&t.b, "", upperBounds)
+}
@@ -0,0 +1,79 @@
+package vmchain
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplate(t *testing.T) {
+	t.Run("counter", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithConstLabels("service", "api"))
+		tpl := c.Template("http_requests_total", "route", "/users", "method", "GET")
+		tpl.Counter().WithAnyLabel("status", 200).Inc()
+		tpl.Counter().WithAnyLabel("status", 500).Inc()
+		tpl.Counter().WithLabel("method", "POST").WithAnyLabel("status", 200).Inc()
+		assert.Equal(t, []string{
+			`http_requests_total{service="api",route="/users",method="GET",status="200"}`,
+			`http_requests_total{service="api",route="/users",method="GET",status="500"}`,
+			`http_requests_total{service="api",route="/users",method="POST",status="200"}`,
+		}, set.ListMetricNames())
+	})
+	t.Run("all types", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		tpl := c.Template("op", "stage", "auth")
+		tpl.Gauge(nil).L("kind", "g").Set(1)
+		tpl.FloatCounter().L("kind", "f").Add(1)
+		tpl.Histogram().L("kind", "h").Update(1)
+		tpl.SummaryExt(time.Minute, []float64{0.5}).L("kind", "s").Update(1)
+		tpl.PrometheusHistogram().L("kind", "p").Update(1)
+		assert.Len(t, set.ListMetricNames(), 5)
+		assert.Contains(t, set.ListMetricNames(), `op{stage="auth",kind="g"}`)
+	})
+	t.Run("concurrent", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		tpl := c.Template("jobs_total", "queue", "default")
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					tpl.Counter().WithLabel("worker", strconv.Itoa(i%2)).Inc()
+				}
+			}(i)
+		}
+		wg.Wait()
+		assert.Equal(t, uint64(400), tpl.Counter().WithLabel("worker", "0").Get())
+		assert.Equal(t, uint64(400), tpl.Counter().WithLabel("worker", "1").Get())
+	})
+	t.Run("odd", func(t *testing.T) {
+		assert.Panics(t, func() { NewChain().Template("jobs_total", "queue") })
+	})
+}
+
+func BenchmarkTemplate(b *testing.B) {
+	b.Run("template", func(b *testing.B) {
+		b.ReportAllocs()
+		tpl := NewChain(WithVMSet(metrics.NewSet())).Template("http_requests_total", "route", "/users", "method", "GET")
+		for i := 0; i < b.N; i++ {
+			tpl.Counter().WithLabel("status", "200").Inc()
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		tpl := NewChain(WithVMSet(metrics.NewSet())).Template("http_requests_total", "route", "/users", "method", "GET")
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				tpl.Counter().WithLabel("status", "200").Inc()
+			}
+		})
+	})
+}
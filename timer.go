@@ -0,0 +1,98 @@
+package vmchain
+
+import (
+	"sync"
+	"time"
+)
+
+// Timer measures duration between chain's Start and Stop calls and records it to the histogram or summary.
+//
+// Labels may be added to the timer until Stop call, e.g. the status of measured operation. Timer must not be used
+// after Stop.
+type Timer interface {
+	WithLabel(name, value string) Timer
+	L(name, value string) Timer
+	WithAnyLabel(name string, value any) Timer
+	AL(name string, value any) Timer
+	// Unit sets the unit of recorded value. By default, duration records in seconds.
+	Unit(unit time.Duration) Timer
+	// Stop records elapsed time and returns it.
+	Stop() time.Duration
+}
+
+// timed represents a chain that may be measured by timer.
+type timed interface {
+	Update(value float64)
+	self() *builder
+}
+
+type timer struct {
+	t     timed
+	start time.Time
+	unit  time.Duration
+}
+
+var timerPool = sync.Pool{New: func() any { return &timer{} }}
+
+func startTimer(t timed) Timer {
+	if t.self().raw {
+		// Chain is disabled, so don't measure time. Update just releases the chain.
+		t.Update(0)
+		return nopTimer{}
+	}
+	tt := timerPool.Get().(*timer)
+	tt.t, tt.start, tt.unit = t, time.Now(), time.Second
+	return tt
+}
+
+func (t *timer) WithLabel(name, value string) Timer {
+	if t.t != nil {
+		t.t.self().setLabel(name, value)
+	}
+	return t
+}
+
+func (t *timer) L(name, value string) Timer {
+	return t.WithLabel(name, value)
+}
+
+func (t *timer) WithAnyLabel(name string, value any) Timer {
+	if t.t != nil {
+		t.t.self().setAnyLabel(name, value)
+	}
+	return t
+}
+
+func (t *timer) AL(name string, value any) Timer {
+	return t.WithAnyLabel(name, value)
+}
+
+func (t *timer) Unit(unit time.Duration) Timer {
+	if unit > 0 {
+		t.unit = unit
+	}
+	return t
+}
+
+func (t *timer) Stop() time.Duration {
+	if t.t == nil {
+		return 0
+	}
+	d := time.Since(t.start)
+	t.t.Update(float64(d) / float64(t.unit))
+	t.t = nil
+	timerPool.Put(t)
+	return d
+}
+
+// nopTimer is returned by Start method of disabled chain (see WithDisabled and Chain.SetEnabled).
+type nopTimer struct{}
+
+func (n nopTimer) WithLabel(string, string) Timer { return n }
+func (n nopTimer) L(string, string) Timer         { return n }
+func (n nopTimer) WithAnyLabel(string, any) Timer { return n }
+func (n nopTimer) AL(string, any) Timer           { return n }
+func (n nopTimer) Unit(time.Duration) Timer       { return n }
+
+// Stop doesn't measure time and always returns zero.
+func (nopTimer) Stop() time.Duration { return 0 }
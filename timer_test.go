@@ -0,0 +1,60 @@
+package vmchain
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimer(t *testing.T) {
+	t.Run("histogram", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		tm := c.Histogram("op_duration_seconds").WithLabel("op", "read").Start()
+		d := tm.WithLabel("status", "ok").Stop()
+		assert.True(t, d > 0)
+		var buf bytes.Buffer
+		set.WritePrometheus(&buf)
+		assert.Contains(t, buf.String(), `op_duration_seconds_count{op="read",status="ok"} 1`)
+	})
+	t.Run("summary", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		func() {
+			tm := c.Summary("op_duration_seconds").L("op", "write").Start()
+			defer tm.AL("status", 500).Stop()
+		}()
+		var buf bytes.Buffer
+		set.WritePrometheus(&buf)
+		assert.Contains(t, buf.String(), `op_duration_seconds_count{op="write",status="500"} 1`)
+	})
+	t.Run("unit", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set))
+		tm := c.PrometheusHistogramExt("op_duration_ms", []float64{1, 1000}).Start().Unit(time.Millisecond)
+		time.Sleep(2 * time.Millisecond)
+		tm.Stop()
+		var buf bytes.Buffer
+		set.WritePrometheus(&buf)
+		assert.Contains(t, buf.String(), `op_duration_ms_bucket{le="1"} 0`)
+		assert.Contains(t, buf.String(), `op_duration_ms_bucket{le="1000"} 1`)
+	})
+}
+
+func BenchmarkTimer(b *testing.B) {
+	b.Run("histogram", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			hfn().Start().WithLabel("status", "ok").Stop()
+		}
+	})
+	b.Run("summary", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sfn().Start().Stop()
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package vmchain
+
+import (
+	"io"
+	"time"
+)
+
+func (c *chain) Sweep() int {
+	if c.ttl == 0 {
+		return 0
+	}
+	deadline := time.Now().Add(-c.ttl).Unix()
+	var n int
+	for _, st := range c.storages() {
+		n += c.sweep(st, deadline)
+	}
+	return n
+}
+
+func (c *chain) Close() (err error) {
+	c.once.Do(func() {
+		if c.done != nil {
+			close(c.done)
+		}
+		if cl, ok := c.be.(io.Closer); ok {
+			err = cl.Close()
+		}
+	})
+	return
+}
+
+// sweep unregisters all metrics in st that weren't used since deadline.
+func (c *chain) sweep(st *storage, deadline int64) (n int) {
+	for i := range st.shards {
+		sh := &st.shards[i]
+		sh.mux.Lock()
+		for fullName, e := range sh.idx {
+			if e.touch.Load() < deadline {
+				c.evict(sh, fullName, e)
+				n++
+			}
+		}
+		sh.mux.Unlock()
+	}
+	return
+}
+
+// evict removes metric from shard and backend. Caller must hold write lock.
+func (c *chain) evict(sh *shard, fullName string, e *entry) {
+	delete(sh.idx, fullName)
+	e.dead.Store(true)
+	c.be.Unregister(fullName)
+	if e.fam != nil {
+		if e.ovf {
+			e.fam.overflow.Add(^uint64(0))
+		} else {
+			e.fam.series.Add(^uint64(0))
+		}
+	}
+}
+
+func (c *chain) sweeper() {
+	t := time.NewTicker(c.swi)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			c.Sweep()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *chain) storages() [6]*storage {
+	return [...]*storage{&c.gst, &c.cst, &c.fst, &c.hst, &c.sst, &c.pst}
+}
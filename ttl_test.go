@@ -0,0 +1,69 @@
+package vmchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// age moves last touch time of the series back by d.
+func age(c Chain, st func(*chain) *storage, fullName string, d time.Duration) {
+	e, _ := st(c.(*chain)).get(fullName)
+	e.touch.Add(-int64(d / time.Second))
+}
+
+func TestTTL(t *testing.T) {
+	cst := func(c *chain) *storage { return &c.cst }
+	t.Run("sweep", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithTTL(time.Minute, 0))
+		c.Counter("sessions_total").WithLabel("session", "a").Inc()
+		c.Counter("sessions_total").WithLabel("session", "b").Inc()
+		c.Summary("session_duration_seconds").WithLabel("session", "a").Update(1)
+		age(c, cst, `sessions_total{session="a"}`, time.Hour)
+		age(c, func(c *chain) *storage { return &c.sst }, `session_duration_seconds{session="a"}`, time.Hour)
+
+		assert.Equal(t, 2, c.Sweep())
+		assert.Equal(t, []string{`sessions_total{session="b"}`}, set.ListMetricNames())
+		assert.Equal(t, FamilyStats{Series: 1}, c.FamilyStats("sessions_total"))
+
+		// Evicted series registers again from scratch.
+		c.Counter("sessions_total").WithLabel("session", "a").Inc()
+		assert.Equal(t, uint64(1), c.Counter("sessions_total").WithLabel("session", "a").Get())
+		assert.Equal(t, 0, c.Sweep())
+	})
+	t.Run("touch", func(t *testing.T) {
+		c := NewChain(WithVMSet(metrics.NewSet()), WithTTL(time.Minute, 0))
+		c.Counter("sessions_total").WithLabel("session", "a").Inc()
+		age(c, cst, `sessions_total{session="a"}`, time.Hour)
+		c.Counter("sessions_total").WithLabel("session", "a").Inc()
+		assert.Equal(t, 0, c.Sweep())
+	})
+	t.Run("disabled", func(t *testing.T) {
+		c := NewChain(WithVMSet(metrics.NewSet()))
+		c.Counter("sessions_total").WithLabel("session", "a").Inc()
+		assert.Equal(t, 0, c.Sweep())
+	})
+	t.Run("background", func(t *testing.T) {
+		set := metrics.NewSet()
+		c := NewChain(WithVMSet(set), WithTTL(time.Minute, time.Millisecond))
+		defer func() { _ = c.Close() }()
+		c.Counter("sessions_total").WithLabel("session", "a").Inc()
+		age(c, cst, `sessions_total{session="a"}`, time.Hour)
+		assert.Eventually(t, func() bool {
+			return len(set.ListMetricNames()) == 0
+		}, time.Second, time.Millisecond)
+	})
+}
+
+func BenchmarkTTL(b *testing.B) {
+	b.Run("touch", func(b *testing.B) {
+		b.ReportAllocs()
+		c := NewChain(WithVMSet(metrics.NewSet()), WithTTL(time.Minute, 0))
+		for i := 0; i < b.N; i++ {
+			c.Counter("sessions_total").WithLabel("session", "a").Inc()
+		}
+	})
+}
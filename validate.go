@@ -0,0 +1,119 @@
+package vmchain
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/koykov/byteconv"
+)
+
+// ValidationMode defines how invalid metric and label names are handled.
+type ValidationMode uint8
+
+const (
+	// ValidationModeNone disables validation (default).
+	ValidationModeNone ValidationMode = iota
+	// ValidationModeStrict panics on invalid name.
+	ValidationModeStrict
+	// ValidationModeSanitize replaces invalid characters with underscore in-place.
+	ValidationModeSanitize
+	// ValidationModeReport passes the error to error handler (see WithErrorHandler) and drops the metric.
+	ValidationModeReport
+)
+
+var (
+	ErrInvalidMetricName = errors.New("invalid metric name")
+	ErrInvalidLabelName  = errors.New("invalid label name")
+	ErrInvalidLabelValue = errors.New("invalid label value")
+)
+
+// validate checks name in buf[off:] according mode.
+//
+// Metric names must match [a-zA-Z_:][a-zA-Z0-9_:]*, label names must match [a-zA-Z_][a-zA-Z0-9_]*. Returns possibly
+// modified buffer and error if name is invalid and can't be fixed.
+func validate(buf []byte, off int, label bool, mode ValidationMode) ([]byte, error) {
+	if mode == ValidationModeNone || validName(buf[off:], label) {
+		return buf, nil
+	}
+	if mode == ValidationModeSanitize && len(buf) > off {
+		return sanitize(buf, off, label), nil
+	}
+	err := ErrInvalidMetricName
+	if label {
+		err = ErrInvalidLabelName
+	}
+	err = fmt.Errorf("%w: %q", err, buf[off:])
+	if mode == ValidationModeStrict {
+		panic(err)
+	}
+	return buf, err
+}
+
+// reported is a set of invalid names already passed to error handler in ValidationModeReport. Each name is reported
+// once, so next uses of the name cost no allocations.
+type reported struct {
+	mux           sync.RWMutex
+	names, labels map[string]struct{}
+}
+
+func (r *reported) has(name []byte, label bool) bool {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	_, ok := r.set(label)[byteconv.B2S(name)]
+	return ok
+}
+
+func (r *reported) add(name []byte, label bool) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.names == nil {
+		r.names, r.labels = make(map[string]struct{}), make(map[string]struct{})
+	}
+	r.set(label)[string(name)] = struct{}{}
+}
+
+func (r *reported) set(label bool) map[string]struct{} {
+	if label {
+		return r.labels
+	}
+	return r.names
+}
+
+func validName(s []byte, label bool) bool {
+	if len(s) == 0 {
+		return false
+	}
+	if isDigit(s[0]) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isNameChar(s[i], label) {
+			return false
+		}
+	}
+	return true
+}
+
+func sanitize(buf []byte, off int, label bool) []byte {
+	for i := off; i < len(buf); i++ {
+		if !isNameChar(buf[i], label) {
+			buf[i] = '_'
+		}
+	}
+	if isDigit(buf[off]) {
+		// Name can't start with digit, so prepend underscore.
+		buf = append(buf, 0)
+		copy(buf[off+1:], buf[off:])
+		buf[off] = '_'
+	}
+	return buf
+}
+
+func isNameChar(c byte, label bool) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || isDigit(c) || c == '_' || (c == ':' && !label)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,107 @@
+package vmchain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("name", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			label bool
+			ok    bool
+		}{
+			{"http_requests_total", false, true},
+			{"ns:http_requests_total", false, true},
+			{"_private", false, true},
+			{"http-requests", false, false},
+			{"1st_metric", false, false},
+			{"", false, false},
+			{"method", true, true},
+			{"__name", true, true},
+			{"ns:method", true, false},
+			{"user id", true, false},
+			{"9", true, false},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				assert.Equal(t, tc.ok, validName([]byte(tc.name), tc.label))
+			})
+		}
+	})
+	t.Run("sanitize", func(t *testing.T) {
+		var b builder
+		b.val = ValidationModeSanitize
+		b.setName("http-requests.total")
+		b.setLabel("user id", "1")
+		b.setAnyLabel("2nd", 2)
+		assert.True(t, b.ok())
+		assert.Equal(t, `http_requests_total{user_id="1",_2nd="2"}`, b.commit())
+	})
+	t.Run("strict", func(t *testing.T) {
+		c := NewChain(WithValidation(ValidationModeStrict))
+		assert.Panics(t, func() { c.Counter("http-requests").Inc() })
+		assert.Panics(t, func() { c.Counter("http_requests").WithLabel("user-id", "1").Inc() })
+		assert.NotPanics(t, func() { c.Counter("vmchain_strict_total").WithLabel("userID", "1").Inc() })
+	})
+	t.Run("report", func(t *testing.T) {
+		set := metrics.NewSet()
+		var errs []error
+		c := NewChain(WithVMSet(set), WithValidation(ValidationModeReport), WithErrorHandler(func(err error) {
+			errs = append(errs, err)
+		}))
+		c.Counter("http-requests").Inc()
+		c.Counter("http_requests").WithLabel("user-id", "1").Inc()
+		c.Counter("http_requests").WithLabel("userID", "1").Inc()
+		assert.Len(t, errs, 2)
+		assert.True(t, errors.Is(errs[0], ErrInvalidMetricName))
+		assert.True(t, errors.Is(errs[1], ErrInvalidLabelName))
+		assert.Equal(t, []string{`http_requests{userID="1"}`}, set.ListMetricNames())
+
+		// Known invalid names are reported once.
+		c.Counter("http-requests").Inc()
+		c.Sub("db").Counter("http_requests").WithLabel("user-id", "1").Inc()
+		assert.Len(t, errs, 2)
+	})
+	t.Run("report allocs", func(t *testing.T) {
+		if raceEnabled {
+			t.Skip("allocations are inaccurate under race detector")
+		}
+		var n int
+		c := NewChain(WithVMSet(metrics.NewSet()), WithValidation(ValidationModeReport), WithErrorHandler(func(error) {
+			n++
+		}))
+		allocs := testing.AllocsPerRun(100, func() {
+			c.Counter("bad-name").Inc()
+			c.Counter("requests_total").WithLabel("bad-label", "1").Inc()
+		})
+		assert.Equal(t, 0.0, allocs)
+		assert.Equal(t, 2, n)
+	})
+}
+
+func BenchmarkValidate(b *testing.B) {
+	b.Run("strict", func(b *testing.B) {
+		b.ReportAllocs()
+		c := NewChain(WithValidation(ValidationModeStrict))
+		for i := 0; i < b.N; i++ {
+			c.Counter("myservice_validated_counter").
+				WithLabel("groupID", "foobar").
+				WithLabel("countryID", "123").
+				Inc()
+		}
+	})
+	b.Run("sanitize", func(b *testing.B) {
+		b.ReportAllocs()
+		c := NewChain(WithValidation(ValidationModeSanitize))
+		for i := 0; i < b.N; i++ {
+			c.Counter("myservice-sanitized-counter").
+				WithLabel("group-id", "foobar").
+				Inc()
+		}
+	})
+}
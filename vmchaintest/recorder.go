@@ -0,0 +1,343 @@
+// Package vmchaintest provides recording chain to test metrics of the code that uses vmchain.
+package vmchaintest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koykov/vmchain"
+)
+
+// Op is a recorded operation of the metric.
+type Op struct {
+	// Name is a metric name without labels.
+	Name string
+	// Labels of the metric.
+	Labels map[string]string
+	// Op is an operation name: add, sub, set, inc, dec, update or unregister.
+	Op string
+	// Value is an operation argument (1 for inc and dec).
+	Value float64
+}
+
+// Recorder is a chain that keeps metrics in memory and records all operations with them.
+//
+// Each test should use its own recorder, so tests may run in parallel.
+type Recorder struct {
+	vmchain.Chain
+	mux  sync.Mutex
+	ops  []Op
+	idx  map[string]*metric
+	keys []string
+}
+
+// New makes recorder with given options of the chain. Options that change the backend must not be used.
+func New(options ...vmchain.Option) *Recorder {
+	r := &Recorder{idx: make(map[string]*metric)}
+	r.Chain = vmchain.NewChain(append(options, vmchain.WithBackend((*backend)(r)))...)
+	return r
+}
+
+// Ops returns all recorded operations.
+func (r *Recorder) Ops() []Op {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return append([]Op(nil), r.ops...)
+}
+
+// Reset clears recorded operations and values of all metrics.
+func (r *Recorder) Reset() {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.ops = r.ops[:0]
+	for _, m := range r.idx {
+		m.v, m.obs = 0, nil
+	}
+}
+
+// CounterValue returns value of counter or float counter name with labels.
+func (r *Recorder) CounterValue(name string, labels map[string]string) float64 {
+	return r.value(name, labels)
+}
+
+// GaugeValue returns value of gauge name with labels.
+func (r *Recorder) GaugeValue(name string, labels map[string]string) float64 {
+	return r.value(name, labels)
+}
+
+// Observations returns values observed by histogram or summary name with labels.
+func (r *Recorder) Observations(name string, labels map[string]string) []float64 {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if m := r.find(name, labels); m != nil {
+		return append([]float64(nil), m.obs...)
+	}
+	return nil
+}
+
+// AssertCounter checks value of counter or float counter name with labels.
+func (r *Recorder) AssertCounter(t testing.TB, name string, labels map[string]string, want float64) bool {
+	t.Helper()
+	return r.assert(t, "counter", name, labels, want)
+}
+
+// AssertGauge checks value of gauge name with labels.
+func (r *Recorder) AssertGauge(t testing.TB, name string, labels map[string]string, want float64) bool {
+	t.Helper()
+	return r.assert(t, "gauge", name, labels, want)
+}
+
+// AssertObservations checks values observed by histogram or summary name with labels.
+func (r *Recorder) AssertObservations(t testing.TB, name string, labels map[string]string, want ...float64) bool {
+	t.Helper()
+	got := r.Observations(name, labels)
+	if len(got) != len(want) {
+		t.Errorf("%s: got observations %v, want %v", key(name, labels), got, want)
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("%s: got observations %v, want %v", key(name, labels), got, want)
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Recorder) assert(t testing.TB, typ, name string, labels map[string]string, want float64) bool {
+	t.Helper()
+	m := r.lookup(name, labels)
+	if m == nil {
+		t.Errorf("%s %s not found, known metrics: %s", typ, key(name, labels), strings.Join(r.known(), ", "))
+		return false
+	}
+	if got := m.Get(); got != want {
+		t.Errorf("%s %s: got %v, want %v", typ, key(name, labels), got, want)
+		return false
+	}
+	return true
+}
+
+// value returns value of the metric. Gauge callback is called outside the lock, since it may use the recorder.
+func (r *Recorder) value(name string, labels map[string]string) float64 {
+	if m := r.lookup(name, labels); m != nil {
+		return m.Get()
+	}
+	return 0
+}
+
+func (r *Recorder) lookup(name string, labels map[string]string) *metric {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.find(name, labels)
+}
+
+// find returns metric with name and labels regardless labels order. Caller must hold the lock.
+func (r *Recorder) find(name string, labels map[string]string) *metric {
+	if m, ok := r.idx[key(name, labels)]; ok {
+		return m
+	}
+	return nil
+}
+
+func (r *Recorder) known() []string {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return append([]string(nil), r.keys...)
+}
+
+func (r *Recorder) record(m *metric, op string, value float64) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	switch op {
+	case "add", "inc":
+		m.v += value
+	case "sub", "dec":
+		m.v -= value
+	case "set":
+		m.v = value
+	case "update":
+		m.obs = append(m.obs, value)
+	}
+	r.ops = append(r.ops, Op{Name: m.name, Labels: m.labels, Op: op, Value: value})
+}
+
+// backend is a vmchain.Backend of the recorder.
+type backend Recorder
+
+func (b *backend) metric(fullName string, f func() float64) *metric {
+	r := (*Recorder)(b)
+	name, labels := parse(fullName)
+	k := key(name, labels)
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if m, ok := r.idx[k]; ok {
+		return m
+	}
+	m := &metric{r: r, name: name, labels: labels, f: f}
+	r.idx[k] = m
+	r.keys = append(r.keys, k)
+	return m
+}
+
+func (b *backend) Gauge(fullName string, f func() float64) vmchain.GaugeMetric {
+	return b.metric(fullName, f)
+}
+
+func (b *backend) Counter(fullName string) vmchain.CounterMetric {
+	return (*counter)(b.metric(fullName, nil))
+}
+
+func (b *backend) FloatCounter(fullName string) vmchain.FloatCounterMetric {
+	return b.metric(fullName, nil)
+}
+
+func (b *backend) Histogram(fullName string) vmchain.HistogramMetric {
+	return b.metric(fullName, nil)
+}
+
+func (b *backend) Summary(fullName string, _ time.Duration, _ []float64) vmchain.SummaryMetric {
+	return b.metric(fullName, nil)
+}
+
+func (b *backend) PrometheusHistogram(fullName string, _ []float64) vmchain.PrometheusHistogramMetric {
+	return b.metric(fullName, nil)
+}
+
+func (b *backend) Unregister(fullName string) bool {
+	r := (*Recorder)(b)
+	name, labels := parse(fullName)
+	k := key(name, labels)
+	r.mux.Lock()
+	m, ok := r.idx[k]
+	if ok {
+		delete(r.idx, k)
+		for i := range r.keys {
+			if r.keys[i] == k {
+				r.keys = append(r.keys[:i], r.keys[i+1:]...)
+				break
+			}
+		}
+	}
+	r.mux.Unlock()
+	if ok {
+		r.record(m, "unregister", 0)
+	}
+	return ok
+}
+
+// metric is a recorded metric of any type.
+type metric struct {
+	r      *Recorder
+	name   string
+	labels map[string]string
+	f      func() float64
+	// Value of counter or gauge.
+	v float64
+	// Observations of histogram or summary.
+	obs []float64
+}
+
+func (m *metric) Add(value float64) { m.r.record(m, "add", value) }
+func (m *metric) Sub(value float64) { m.r.record(m, "sub", value) }
+func (m *metric) Set(value float64) { m.r.record(m, "set", value) }
+func (m *metric) Inc()              { m.r.record(m, "inc", 1) }
+func (m *metric) Dec()              { m.r.record(m, "dec", 1) }
+
+func (m *metric) Get() float64 {
+	if m.f != nil {
+		return m.f()
+	}
+	m.r.mux.Lock()
+	defer m.r.mux.Unlock()
+	return m.v
+}
+
+func (m *metric) Update(value float64) { m.r.record(m, "update", value) }
+
+func (m *metric) UpdateDuration(startTime time.Time) { m.Update(time.Since(startTime).Seconds()) }
+
+// VisitNonZeroBuckets does nothing, since recorder keeps raw observations.
+func (m *metric) VisitNonZeroBuckets(func(vmrange string, count uint64)) {}
+
+func (m *metric) Reset() {
+	m.r.mux.Lock()
+	defer m.r.mux.Unlock()
+	m.obs = nil
+}
+
+// counter is an integer view of the metric.
+type counter metric
+
+func (c *counter) Add(value int)        { c.m().Add(float64(value)) }
+func (c *counter) AddInt64(value int64) { c.m().Add(float64(value)) }
+func (c *counter) Set(value uint64)     { c.m().Set(float64(value)) }
+func (c *counter) Inc()                 { c.m().Inc() }
+func (c *counter) Dec()                 { c.m().Dec() }
+func (c *counter) Get() uint64          { return uint64(math.Max(c.m().Get(), 0)) }
+func (c *counter) m() *metric           { return (*metric)(c) }
+
+// parse splits full name like `name{k0="v0",k1="v1"}` to name and labels.
+func parse(fullName string) (string, map[string]string) {
+	i := strings.IndexByte(fullName, '{')
+	if i < 0 {
+		return fullName, nil
+	}
+	name, s := fullName[:i], fullName[i+1:]
+	labels := make(map[string]string)
+	for len(s) > 1 {
+		eq := strings.Index(s, `="`)
+		if eq < 0 {
+			break
+		}
+		k := s[:eq]
+		s = s[eq+2:]
+		var v strings.Builder
+		j := 0
+		for ; j < len(s) && s[j] != '"'; j++ {
+			c := s[j]
+			if c == '\\' && j+1 < len(s) {
+				j++
+				if c = s[j]; c == 'n' {
+					c = '\n'
+				}
+			}
+			v.WriteByte(c)
+		}
+		labels[k] = v.String()
+		if j+1 >= len(s) {
+			break
+		}
+		// Skip closing quote and comma.
+		s = s[j+2:]
+	}
+	return name, labels
+}
+
+// key makes string representation of the metric with sorted labels.
+func key(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('{')
+	for i, k := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		_, _ = fmt.Fprintf(&b, "%s=%q", k, labels[k])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
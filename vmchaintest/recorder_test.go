@@ -0,0 +1,96 @@
+package vmchaintest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/koykov/vmchain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Run("counter", func(t *testing.T) {
+		t.Parallel()
+		r := New()
+		r.Counter("requests_total").WithLabel("method", "GET").WithIntLabel("code", 200).Inc()
+		r.Counter("requests_total").WithIntLabel("code", 200).WithLabel("method", "GET").Add(2)
+		r.AssertCounter(t, "requests_total", map[string]string{"code": "200", "method": "GET"}, 3)
+		assert.Equal(t, []Op{
+			{Name: "requests_total", Labels: map[string]string{"code": "200", "method": "GET"}, Op: "inc", Value: 1},
+			{Name: "requests_total", Labels: map[string]string{"code": "200", "method": "GET"}, Op: "add", Value: 2},
+		}, r.Ops())
+	})
+	t.Run("gauge", func(t *testing.T) {
+		t.Parallel()
+		r := New(vmchain.WithNamespace("api"), vmchain.WithConstLabels("service", "users"))
+		r.Gauge("sessions", nil).Set(5)
+		r.Gauge("sessions", nil).Dec()
+		r.FloatCounter("bytes_total").Add(1.5)
+		r.AssertGauge(t, "api_sessions", map[string]string{"service": "users"}, 4)
+		r.AssertCounter(t, "api_bytes_total", map[string]string{"service": "users"}, 1.5)
+	})
+	t.Run("gauge callback", func(t *testing.T) {
+		t.Parallel()
+		r := New()
+		r.Counter("requests_total").Add(3)
+		// Callback reads the recorder, so it must be called outside the lock.
+		r.Gauge("requests_double", func() float64 { return 2 * r.CounterValue("requests_total", nil) }).Get()
+		assert.Equal(t, 6.0, r.GaugeValue("requests_double", nil))
+		r.AssertGauge(t, "requests_double", nil, 6)
+	})
+	t.Run("observations", func(t *testing.T) {
+		t.Parallel()
+		r := New()
+		r.Histogram("latency_seconds").WithLabel("route", `/"x"`).Update(0.5)
+		r.Summary("latency_seconds").WithLabel("route", `/"x"`).Update(1)
+		r.PrometheusHistogram("size_kb").Update(3)
+		r.AssertObservations(t, "latency_seconds", map[string]string{"route": `/"x"`}, 0.5, 1)
+		r.AssertObservations(t, "size_kb", nil, 3)
+	})
+	t.Run("reset", func(t *testing.T) {
+		t.Parallel()
+		r := New()
+		r.Counter("requests_total").Inc()
+		r.Histogram("latency_seconds").Update(1)
+		r.Reset()
+		assert.Empty(t, r.Ops())
+		r.AssertCounter(t, "requests_total", nil, 0)
+		assert.Empty(t, r.Observations("latency_seconds", nil))
+		r.Counter("requests_total").Inc()
+		r.AssertCounter(t, "requests_total", nil, 1)
+	})
+	t.Run("unregister", func(t *testing.T) {
+		t.Parallel()
+		r := New()
+		r.Counter("requests_total").WithLabel("method", "GET").Inc()
+		assert.True(t, r.Counter("requests_total").WithLabel("method", "GET").Unregister())
+		assert.Equal(t, "unregister", r.Ops()[1].Op)
+		assert.Equal(t, 0.0, r.CounterValue("requests_total", map[string]string{"method": "GET"}))
+	})
+	t.Run("assert failure", func(t *testing.T) {
+		t.Parallel()
+		r := New()
+		r.Counter("requests_total").Inc()
+		tt := &fakeT{TB: t}
+		assert.False(t, r.AssertCounter(tt, "requests_total", nil, 2))
+		assert.False(t, r.AssertCounter(tt, "requests_total", map[string]string{"method": "GET"}, 1))
+		assert.False(t, r.AssertObservations(tt, "latency_seconds", nil, 1))
+		assert.Equal(t, []string{
+			"counter requests_total: got 1, want 2",
+			`counter requests_total{method="GET"} not found, known metrics: requests_total`,
+			"latency_seconds: got observations [], want [1]",
+		}, tt.errs)
+	})
+}
+
+// fakeT collects errors instead of failing the test.
+type fakeT struct {
+	testing.TB
+	errs []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...any) {
+	t.errs = append(t.errs, fmt.Sprintf(format, args...))
+}